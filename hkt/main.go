@@ -7,7 +7,6 @@ import (
 	"os/signal"
 
 	"hookt.dev/cmd/pkg/command"
-	"hookt.dev/cmd/pkg/errors"
 	"hookt.dev/cmd/pkg/trace"
 
 	"github.com/spf13/cobra"
@@ -44,41 +43,122 @@ func die(err error) {
 
 func newCommand(ctx context.Context, app *command.App) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:              "hkt",
-		Short:            "CLI for testing",
-		Args:             cobra.NoArgs,
-		PersistentPreRun: app.Init,
-		Version:          version,
+		Use:               "hkt",
+		Short:             "CLI for testing",
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: app.Init,
+		Version:           version,
 	}
 
 	cmd.AddCommand(
 		newRunCommand(ctx, app),
+		newValidateCommand(ctx, app),
+		newSchemaCommand(app),
+		newPluginsCommand(app),
 	)
 
 	return cmd
 }
 
 func newRunCommand(ctx context.Context, app *command.App) *cobra.Command {
+	var (
+		parallel int
+		reports  []string
+	)
+
 	cmd := &cobra.Command{
-		Use:  "run",
-		Args: cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, files []string) error {
+		Use:   "run [file|dir|glob]...",
+		Short: "Run workflow files",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if cmd.Flags().Changed("debug") {
 				ctx = trace.WithJob(ctx, trace.LogJob())
 				ctx = trace.WithPattern(ctx, trace.LogPattern())
 				ctx = trace.WithSchedule(ctx, trace.LogSchedule())
 			}
 
-			p, err := os.ReadFile(files[0])
+			files, err := command.Files(args)
 			if err != nil {
-				return errors.New("failed to read file: %w", err)
+				return err
+			}
+
+			sum := app.RunFiles(ctx, files, parallel)
+
+			if err := app.Render(sum); err != nil {
+				return err
 			}
 
-			s, err := app.Engine.Run(ctx, p)
-			if s != nil && len(s.Events) != 0 {
-				app.Render(s.Results())
+			for _, spec := range reports {
+				if err := sum.Report(spec); err != nil {
+					return err
+				}
+			}
+
+			return sum.Err()
+		},
+		Version:      version,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().IntVarP(&parallel, "parallel", "p", 1, "maximum number of workflows to run concurrently")
+	cmd.Flags().StringArrayVar(&reports, "report", nil, "write a report as format=path, where format is junit or json")
+
+	app.RegisterVars(cmd.Flags())
+
+	return cmd
+}
+
+func newValidateCommand(ctx context.Context, app *command.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [file|dir|glob]...",
+		Short: "Check workflow files without running them",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := command.Files(args)
+			if err != nil {
+				return err
 			}
-			return err
+
+			sum := app.ValidateFiles(ctx, files)
+
+			if err := app.Render(sum); err != nil {
+				return err
+			}
+
+			return sum.Err()
+		},
+		Version:      version,
+		SilenceUsage: true,
+	}
+
+	app.RegisterVars(cmd.Flags())
+
+	return cmd
+}
+
+func newSchemaCommand(app *command.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of workflow files",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Println(app.Engine.Schema())
+			return nil
+		},
+		Version:      version,
+		SilenceUsage: true,
+	}
+
+	return cmd
+}
+
+func newPluginsCommand(app *command.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plugins",
+		Short: "List the available plugins",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return app.Render(app.Engine.Plugins())
 		},
 		Version:      version,
 		SilenceUsage: true,
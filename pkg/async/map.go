@@ -1,6 +1,7 @@
 package async
 
 import (
+	"context"
 	"sync"
 )
 
@@ -48,3 +49,65 @@ func (m *Map) Load(key any) (any, bool) {
 
 	return m.m.Load(key)
 }
+
+// LoadContext is like Load, but stops waiting for the key
+// to be stored once ctx is done, returning its error.
+func (m *Map) LoadContext(ctx context.Context, key any) (any, error) {
+	value, _ := m.m.LoadOrStore(key, pool.Get())
+	cond, wait := value.(*sync.Cond)
+	if !wait {
+		return value, nil
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		cond.L.Lock()
+		cond.Broadcast()
+		cond.L.Unlock()
+	})
+	defer stop()
+
+	cond.L.Lock()
+	for {
+		v, _ := m.m.Load(key)
+		if _, ok := v.(*sync.Cond); !ok {
+			break
+		}
+
+		if err := ctx.Err(); err != nil {
+			cond.L.Unlock()
+			return nil, err
+		}
+
+		cond.Wait()
+	}
+	cond.L.Unlock()
+
+	value, _ = m.m.Load(key)
+	return value, nil
+}
+
+// Lookup is like Load, but does not wait for the key to be
+// stored, reporting whether it is stored already.
+func (m *Map) Lookup(key any) (any, bool) {
+	value, ok := m.m.Load(key)
+	if _, wait := value.(*sync.Cond); wait {
+		return nil, false
+	}
+
+	return value, ok
+}
+
+// Keys returns the keys that are stored, leaving out
+// the ones that are only waited for.
+func (m *Map) Keys() []any {
+	var keys []any
+
+	m.m.Range(func(key, value any) bool {
+		if _, wait := value.(*sync.Cond); !wait {
+			keys = append(keys, key)
+		}
+		return true
+	})
+
+	return keys
+}
@@ -1,8 +1,11 @@
 package async_test
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"hookt.dev/cmd/pkg/async"
 
@@ -67,3 +70,60 @@ func TestMap(t *testing.T) {
 		}
 	}
 }
+
+func TestMapLoadContext(t *testing.T) {
+	var m async.Map
+
+	m.Store("set", 1)
+
+	if v, err := m.LoadContext(context.Background(), "set"); err != nil || v != 1 {
+		t.Fatalf("LoadContext()=%v, %v, want 1", v, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := m.LoadContext(ctx, "missing"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("LoadContext()=%v, want deadline exceeded", err)
+	}
+
+	done := make(chan any)
+
+	go func() {
+		v, _ := m.LoadContext(context.Background(), "later")
+		done <- v
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	m.Store("later", 2)
+
+	if v := <-done; v != 2 {
+		t.Errorf("LoadContext()=%v, want 2", v)
+	}
+
+	if keys := m.Keys(); len(keys) != 2 {
+		t.Errorf("Keys()=%v, want set and later", keys)
+	}
+}
+
+func TestMapLookup(t *testing.T) {
+	var m async.Map
+
+	m.Store("set", 1)
+
+	if v, ok := m.Lookup("set"); !ok || v != 1 {
+		t.Fatalf("Lookup()=%v, %t, want 1", v, ok)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// A key that is only waited for is not stored yet.
+	m.LoadContext(ctx, "waited")
+
+	for _, key := range []string{"missing", "waited"} {
+		if v, ok := m.Lookup(key); ok {
+			t.Errorf("Lookup(%q)=%v, want not stored", key, v)
+		}
+	}
+}
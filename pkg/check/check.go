@@ -2,18 +2,36 @@ package check // import "hookt.dev/cmd/pkg/check"
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/itchyny/gojq"
 	"hookt.dev/cmd/pkg/trace"
 )
 
+// ErrTimeout is wrapped by errors of steps that ran out of time.
+var ErrTimeout = errors.New("timed out")
+
+type Status string
+
+const (
+	StatusPass    Status = "pass"
+	StatusFail    Status = "fail"
+	StatusError   Status = "error"
+	StatusTimeout Status = "timeout"
+	StatusSkip    Status = "skip"
+)
+
 type S struct {
 	mu sync.Mutex
 
-	Events Events
+	Records []*Record
 
 	Steps struct {
 		OK   int
@@ -33,41 +51,127 @@ func (s *S) Fail() {
 	s.mu.Unlock()
 }
 
+// Begin starts recording the run of a step.
+func (s *S) Begin(r *Record) *Record {
+	r.Start = time.Now()
+
+	s.mu.Lock()
+	s.Records = append(s.Records, r)
+	s.mu.Unlock()
+
+	return r
+}
+
+// Finish ends recording the step, setting its status from err.
+func (s *S) Finish(r *Record, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.End = time.Now()
+
+	if n := len(r.Attempts); n != 0 {
+		r.Patterns = r.Attempts[n-1].Patterns
+	}
+
+	switch {
+	case err == nil:
+		r.Status = StatusPass
+		s.Steps.OK++
+		return
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		r.Status = StatusTimeout
+	case len(r.Failures()) != 0:
+		r.Status = StatusFail
+	default:
+		r.Status = StatusError
+	}
+
+	r.Error = err.Error()
+	s.Steps.Fail++
+}
+
+// Attempt records a try of the step in ctx that started at start and
+// ended with err, moving the patterns marked during it to the attempt.
+// The step keeps the patterns of its last attempt once finished.
+func (s *S) Attempt(ctx context.Context, start time.Time, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.record(ctx)
+
+	a := Attempt{
+		Start:    start,
+		End:      time.Now(),
+		Patterns: r.Patterns,
+	}
+
+	if err != nil {
+		a.Error = err.Error()
+	}
+
+	r.Attempts = append(r.Attempts, a)
+	r.Patterns = Patterns{}
+}
+
+// Skip ends recording a step that did not run.
+func (s *S) Skip(r *Record, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.End = time.Now()
+	r.Status = StatusSkip
+	r.Error = reason
+}
+
+// Snapshot returns a copy of the records, safe to read while steps
+// still run, in the order of the jobs and steps of the workflow.
+func (s *S) Snapshot() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]Record, 0, len(s.Records))
+	for _, r := range s.Records {
+		c := *r
+		c.Attempts = slices.Clone(r.Attempts)
+		c.Patterns = r.Patterns.clone()
+		records = append(records, c)
+	}
+
+	slices.SortStableFunc(records, func(a, b Record) int {
+		if a.JobIndex != b.JobIndex {
+			return a.JobIndex - b.JobIndex
+		}
+		return a.Index - b.Index
+	})
+
+	return records
+}
+
+// Results returns a result for every step that did not pass.
 func (s *S) Results() []Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	var res []Result
-	for i, e := range s.Events {
-		f := makeFailures(e.Match, false)
-		if len(f) > 0 {
-			res = append(res, Result{
-				Type:     "match",
-				Index:    i,
-				Step:     e.Desc,
-				Failures: f,
-			})
+	for _, r := range s.Records {
+		if r.Status == StatusPass {
 			continue
 		}
 
-		f = makeFailures(e.Fail, true)
-		if len(f) > 0 {
-			res = append(res, Result{
-				Type:     "fail",
-				Index:    i,
-				Step:     e.Desc,
-				Failures: f,
-			})
-			continue
+		typ, f := r.failures()
+		if len(f) == 0 {
+			typ = string(r.Status)
 		}
 
-		f = makeFailures(e.Pass, false)
-		if len(f) > 0 {
-			res = append(res, Result{
-				Type:     "pass",
-				Index:    i,
-				Step:     e.Desc,
-				Failures: f,
-			})
-			continue
-		}
+		res = append(res, Result{
+			Type:     typ,
+			Job:      r.Job,
+			Step:     r.Step,
+			Desc:     r.Desc,
+			Index:    r.Index,
+			Reason:   r.Error,
+			Failures: f,
+		})
 	}
 	return res
 }
@@ -79,90 +183,201 @@ func (s *S) Trace() trace.PatternTrace {
 				return
 			}
 
-			n, _ := strconv.Atoi(trace.Get(ctx, "step-index"))
-			desc := trace.Get(ctx, "step-desc")
 			group := trace.Get(ctx, "pattern-group")
 			pattern := trace.Get(ctx, "pattern")
 
 			s.mu.Lock()
 			defer s.mu.Unlock()
 
-			if n >= len(s.Events) {
-				for i := len(s.Events); i <= n; i++ {
-					s.Events = append(s.Events, &Event{})
-				}
-			}
-
-			s.Events[n].Desc = desc
-			s.Events[n].MarkPattern(group, pattern, Value{
+			s.record(ctx).MarkPattern(group, pattern, Value{
 				OK: false,
 			})
 		},
 		EqualMatch: func(ctx context.Context, want, got any, ok bool) {
-			n, _ := strconv.Atoi(trace.Get(ctx, "step-index"))
 			group := trace.Get(ctx, "pattern-group")
 			pattern := trace.Get(ctx, "pattern")
 
 			s.mu.Lock()
 			defer s.mu.Unlock()
 
-			s.Events[n].MarkPattern(group, pattern, Value{
+			s.record(ctx).MarkPattern(group, pattern, Value{
 				Want: want,
 				Got:  got,
 				OK:   ok,
 			})
 		},
+		SetError: func(ctx context.Context, got any, reason string) {
+			group := trace.Get(ctx, "pattern-group")
+			pattern := trace.Get(ctx, "pattern")
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			s.record(ctx).MarkPattern(group, pattern, Value{
+				Got:   got,
+				Error: reason,
+				OK:    group == "fail",
+			})
+		},
+		QueryError: func(ctx context.Context, obj any, err error) {
+			group := trace.Get(ctx, "pattern-group")
+			pattern := trace.Get(ctx, "pattern")
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			s.record(ctx).MarkPattern(group, pattern, Value{
+				Got:   obj,
+				Error: err.Error(),
+				OK:    false,
+			})
+		},
+	}
+}
+
+// record returns the record of the step in ctx, adding it
+// when it is not recorded yet; s.mu must be held.
+func (s *S) record(ctx context.Context) *Record {
+	var (
+		job  = trace.Get(ctx, "job")
+		i, _ = strconv.Atoi(trace.Get(ctx, "job-index"))
+		n, _ = strconv.Atoi(trace.Get(ctx, "step-index"))
+	)
+
+	for _, r := range s.Records {
+		if r.Job == job && r.Index == n {
+			return r
+		}
+	}
+
+	r := &Record{
+		Job:      job,
+		JobIndex: i,
+		Step:     trace.Get(ctx, "step"),
+		Desc:     trace.Get(ctx, "step-desc"),
+		Index:    n,
+	}
+
+	s.Records = append(s.Records, r)
+
+	return r
+}
+
+// Record describes the run of a single step.
+type Record struct {
+	Job      string    `json:"job"`
+	JobIndex int       `json:"job_index"`
+	Step     string    `json:"step"`
+	Plugin   string    `json:"plugin,omitempty"`
+	Desc     string    `json:"desc,omitempty"`
+	Index    int       `json:"index"`
+	Status   Status    `json:"status"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Error    string    `json:"error,omitempty"`
+
+	Attempts []Attempt `json:"attempts,omitempty"`
+
+	Patterns
+}
+
+// Attempt describes a single try of a step that is retried.
+type Attempt struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Error string    `json:"error,omitempty"`
+
+	Patterns
+}
+
+func (r *Record) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// Failures returns the pattern failures recorded for the step.
+func (r *Record) Failures() []Failure {
+	_, f := r.failures()
+	return f
+}
+
+func (r *Record) failures() (string, []Failure) {
+	if f := makeFailures(r.Match, false); len(f) > 0 {
+		return "match", f
 	}
+	if f := makeFailures(r.Fail, true); len(f) > 0 {
+		return "fail", f
+	}
+	if f := makeFailures(r.Until, false); len(f) > 0 {
+		return "until", f
+	}
+	return "pass", makeFailures(r.Pass, false)
 }
 
-func (e *Event) MarkPattern(group, pattern string, v Value) {
+type Patterns struct {
+	Until map[string]Value `json:"until,omitempty"`
+	Match map[string]Value `json:"match,omitempty"`
+	Pass  map[string]Value `json:"pass,omitempty"`
+	Fail  map[string]Value `json:"fail,omitempty"`
+}
+
+func (p Patterns) clone() Patterns {
+	return Patterns{
+		Until: maps.Clone(p.Until),
+		Match: maps.Clone(p.Match),
+		Pass:  maps.Clone(p.Pass),
+		Fail:  maps.Clone(p.Fail),
+	}
+}
+
+func (p *Patterns) MarkPattern(group, pattern string, v Value) {
 	switch group {
+	case "until":
+		if p.Until == nil {
+			p.Until = make(map[string]Value)
+		}
+		p.Until[pattern] = v
 	case "match":
-		if e.Match == nil {
-			e.Match = make(map[string]Value)
+		if p.Match == nil {
+			p.Match = make(map[string]Value)
 		}
-		e.Match[pattern] = v
+		p.Match[pattern] = v
 	case "pass":
-		if e.Pass == nil {
-			e.Pass = make(map[string]Value)
+		if p.Pass == nil {
+			p.Pass = make(map[string]Value)
 		}
-		e.Pass[pattern] = v
+		p.Pass[pattern] = v
 	case "fail":
-		if e.Fail == nil {
-			e.Fail = make(map[string]Value)
+		if p.Fail == nil {
+			p.Fail = make(map[string]Value)
 		}
-		e.Fail[pattern] = v
+		p.Fail[pattern] = v
 	default:
 		panic(fmt.Errorf("unknown group: %q (pattern=%q, ok=%v)", group, pattern, v))
 	}
 }
 
 type Value struct {
-	Got  any  `json:"got,omitempty"`
-	Want any  `json:"want,omitempty"`
-	OK   bool `json:"ok"`
+	Got   any    `json:"got,omitempty"`
+	Want  any    `json:"want,omitempty"`
+	Error string `json:"error,omitempty"`
+	OK    bool   `json:"ok"`
 }
 
-type Event struct {
-	Desc  string           `json:"desc,omitempty"`
-	Match map[string]Value `json:"match"`
-	Pass  map[string]Value `json:"pass,omitempty"`
-	Fail  map[string]Value `json:"fail,omitempty"`
-}
-
-type Events []*Event
-
 type Result struct {
 	Type     string    `json:"type"`
-	Step     string    `json:"step"`
+	Job      string    `json:"job,omitempty"`
+	Step     string    `json:"step,omitempty"`
+	Desc     string    `json:"desc,omitempty"`
 	Index    int       `json:"index"`
-	Failures []Failure `json:"failures"`
+	Reason   string    `json:"reason,omitempty"`
+	Failures []Failure `json:"failures,omitempty"`
 }
 
 type Failure struct {
 	Key      string `json:"key"`
 	Got      any    `json:"got"`
 	Expected any    `json:"expected"`
+	Error    string `json:"error,omitempty"`
 }
 
 func makeFailures(m map[string]Value, ok bool) []Failure {
@@ -173,8 +388,12 @@ func makeFailures(m map[string]Value, ok bool) []Failure {
 				Key:      key,
 				Got:      v.Got,
 				Expected: v.Want,
+				Error:    v.Error,
 			})
 		}
 	}
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].Key < failures[j].Key
+	})
 	return failures
 }
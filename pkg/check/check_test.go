@@ -0,0 +1,112 @@
+package check_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"hookt.dev/cmd/pkg/check"
+	"hookt.dev/cmd/pkg/trace"
+)
+
+func TestFinish(t *testing.T) {
+	cases := []struct {
+		err     error
+		pattern bool
+		status  check.Status
+		typ     string
+	}{
+		{nil, false, check.StatusPass, ""},
+		{errors.New("response did not match pass pattern"), true, check.StatusFail, "pass"},
+		{errors.New("connection refused"), false, check.StatusError, "error"},
+		{fmt.Errorf("step has %w after 1s", check.ErrTimeout), false, check.StatusTimeout, "timeout"},
+		{fmt.Errorf("get: %w", context.DeadlineExceeded), false, check.StatusTimeout, "timeout"},
+	}
+
+	for _, cas := range cases {
+		t.Run(string(cas.status), func(t *testing.T) {
+			var s check.S
+
+			r := s.Begin(&check.Record{Job: "job", Step: "step", Plugin: "http"})
+			if cas.pattern {
+				r.MarkPattern("pass", ".status", check.Value{Got: 500, Want: 200})
+			}
+			s.Finish(r, cas.err)
+
+			if r.Status != cas.status {
+				t.Errorf("got status %q, want %q", r.Status, cas.status)
+			}
+
+			res := s.Results()
+			if cas.typ == "" {
+				if len(res) != 0 {
+					t.Errorf("Results()=%+v, want none", res)
+				}
+				return
+			}
+
+			if len(res) != 1 || res[0].Type != cas.typ || res[0].Reason != cas.err.Error() {
+				t.Errorf("Results()=%+v, want %q result", res, cas.typ)
+			}
+		})
+	}
+}
+
+func TestAttempt(t *testing.T) {
+	var (
+		s   check.S
+		ctx = trace.With(context.Background(), "job", "job")
+	)
+
+	ctx = trace.With(ctx, "step-index", "0")
+
+	r := s.Begin(&check.Record{Job: "job", Step: "step", Plugin: "http"})
+
+	r.MarkPattern("until", ".body.state", check.Value{Got: "pending", Want: "ready"})
+	s.Attempt(ctx, time.Now(), errors.New("response did not match until pattern"))
+
+	r.MarkPattern("until", ".body.state", check.Value{Got: "failed", Want: "ready"})
+	s.Attempt(ctx, time.Now(), errors.New("response did not match until pattern"))
+
+	s.Finish(r, errors.New("gave up after 2 attempts"))
+
+	if len(r.Attempts) != 2 || r.Attempts[0].Until[".body.state"].Got != "pending" {
+		t.Fatalf("Attempts=%+v", r.Attempts)
+	}
+
+	if r.Status != check.StatusFail {
+		t.Errorf("got status %q, want %q", r.Status, check.StatusFail)
+	}
+
+	if res := s.Results(); len(res) != 1 || res[0].Type != "until" || res[0].Failures[0].Got != "failed" {
+		t.Errorf("Results()=%+v, want failure of the last attempt", res)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	var s check.S
+
+	second := s.Begin(&check.Record{Job: "a", JobIndex: 1, Step: "second"})
+	s.Begin(&check.Record{Job: "b", JobIndex: 0, Step: "late", Index: 1})
+	s.Begin(&check.Record{Job: "b", JobIndex: 0, Step: "early", Index: 0})
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		s.Finish(second, errors.New("response did not match pass pattern"))
+	}()
+
+	var got []string
+	for _, r := range s.Snapshot() {
+		got = append(got, r.Job+"/"+r.Step)
+	}
+
+	<-done
+
+	if want := "[b/early b/late a/second]"; fmt.Sprint(got) != want {
+		t.Errorf("Snapshot()=%v, want %s", got, want)
+	}
+}
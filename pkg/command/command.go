@@ -26,26 +26,38 @@ type App struct {
 
 	Engine *hookt.Engine
 
-	debug bool
+	opts     []func(*hookt.Engine)
+	debug    bool
+	vars     []string
+	varFiles []string
 }
 
 func (app *App) Register(f *pflag.FlagSet) {
 	f.BoolVar(&app.debug, "debug", app.debug, "enable debug logging")
 }
 
+// RegisterVars registers the flags setting workflow variables
+// and inputs, for the commands reading workflows.
+func (app *App) RegisterVars(f *pflag.FlagSet) {
+	f.StringArrayVar(&app.vars, "var", nil, "set a workflow variable or input as key=value")
+	f.StringArrayVar(&app.varFiles, "var-file", nil, "set workflow variables and inputs from a YAML file")
+}
+
 func New(name string, opts ...func(*App)) *App {
-	app := &App{
-		Engine: hookt.New(),
-	}
+	app := &App{}
 
 	for _, opt := range opts {
 		opt(app)
 	}
 
+	app.Engine = hookt.New(app.opts...)
+
 	return app
 }
 
-func (app *App) Init(cmd *cobra.Command, args []string) {
+// Init sets up logging and the variables of the engine
+// from the parsed flags.
+func (app *App) Init(cmd *cobra.Command, args []string) error {
 	level := slog.LevelInfo
 	if app.debug {
 		level = slog.LevelDebug
@@ -58,6 +70,15 @@ func (app *App) Init(cmd *cobra.Command, args []string) {
 			TimeFormat: time.Kitchen,
 		}),
 	))
+
+	vars, err := Vars(app.varFiles, app.vars)
+	if err != nil {
+		return err
+	}
+
+	app.Engine.WithVars(vars)
+
+	return nil
 }
 
 func (app *App) Render(v any) error {
@@ -16,6 +16,6 @@ func WithBuildInfo(version, commit, date string) func(app *App) {
 
 func WithEngineOptions(opts ...func(*hookt.Engine)) func(app *App) {
 	return func(app *App) {
-		app.Engine = hookt.New(opts...)
+		app.opts = append(app.opts, opts...)
 	}
 }
@@ -0,0 +1,210 @@
+package command
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"hookt.dev/cmd/pkg/check"
+	"hookt.dev/cmd/pkg/errors"
+	"hookt.dev/cmd/pkg/report"
+
+	"github.com/lmittmann/tint"
+	"golang.org/x/sync/errgroup"
+)
+
+type Summary struct {
+	Files  []*File `json:"files"`
+	Passed int     `json:"passed"`
+	Failed int     `json:"failed"`
+}
+
+func (s *Summary) Err() error {
+	if s.Failed == 0 {
+		return nil
+	}
+	return errors.New("%d of %d workflows failed", s.Failed, len(s.Files))
+}
+
+type File struct {
+	Path     string         `json:"path"`
+	Status   string         `json:"status"`
+	Duration string         `json:"duration,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Results  []check.Result `json:"results,omitempty"`
+
+	S *check.S `json:"-"`
+
+	elapsed time.Duration
+	err     error
+}
+
+// Report writes the summary in the format and to the path
+// given by spec, e.g. junit=out.xml or json=out.json.
+func (s *Summary) Report(spec string) error {
+	suites := make([]report.Suite, len(s.Files))
+
+	for i, f := range s.Files {
+		suites[i] = report.Suite{
+			Name:     f.Path,
+			Duration: f.elapsed,
+			Err:      f.err,
+			S:        f.S,
+		}
+	}
+
+	return report.Write(spec, suites...)
+}
+
+// Files expands the given arguments into a sorted list of workflow
+// files. Directories are walked recursively for *.yaml and *.yml
+// files, other arguments are treated as glob patterns.
+func Files(args []string) ([]string, error) {
+	var (
+		files []string
+		uniq  = make(map[string]struct{})
+	)
+
+	add := func(path string) {
+		if _, ok := uniq[path]; ok {
+			return
+		}
+		uniq[path] = struct{}{}
+		files = append(files, path)
+	}
+
+	for _, arg := range args {
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, errors.New("invalid pattern %q: %w", arg, err)
+		}
+
+		if len(matches) == 0 {
+			return nil, errors.New("no files matching %q", arg)
+		}
+
+		for _, match := range matches {
+			fi, err := os.Stat(match)
+			if err != nil {
+				return nil, errors.New("failed to stat %q: %w", match, err)
+			}
+
+			if !fi.IsDir() {
+				add(match)
+				continue
+			}
+
+			var found []string
+
+			err = filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() && isWorkflow(path) {
+					found = append(found, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, errors.New("failed to read directory %q: %w", match, err)
+			}
+
+			slices.Sort(found)
+
+			for _, path := range found {
+				add(path)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+func isWorkflow(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+// RunFiles runs every workflow file, at most parallel of them
+// at once, and summarizes their results.
+func (app *App) RunFiles(ctx context.Context, files []string, parallel int) *Summary {
+	var (
+		g   errgroup.Group
+		sum = &Summary{
+			Files: make([]*File, len(files)),
+		}
+	)
+
+	g.SetLimit(max(parallel, 1))
+
+	for i, path := range files {
+		f := &File{Path: path}
+		sum.Files[i] = f
+
+		g.Go(func() error {
+			start := time.Now()
+
+			err := app.runFile(ctx, f)
+
+			f.elapsed = time.Since(start)
+			f.Duration = f.elapsed.Round(time.Millisecond).String()
+
+			if err != nil {
+				f.Status = "fail"
+				f.Error = err.Error()
+				f.err = err
+
+				slog.Error("workflow failure",
+					"file", path,
+					tint.Err(err),
+				)
+
+				return nil
+			}
+
+			f.Status = "pass"
+
+			slog.Info("workflow pass",
+				"file", path,
+			)
+
+			return nil
+		})
+	}
+
+	g.Wait()
+
+	for _, f := range sum.Files {
+		if f.Status == "pass" {
+			sum.Passed++
+		} else {
+			sum.Failed++
+		}
+	}
+
+	return sum
+}
+
+func (app *App) runFile(ctx context.Context, f *File) error {
+	p, err := os.ReadFile(f.Path)
+	if err != nil {
+		return errors.New("failed to read file: %w", err)
+	}
+
+	s, err := app.Engine.Run(ctx, p)
+	if s != nil {
+		f.S = s
+		f.Results = s.Results()
+	}
+
+	return err
+}
@@ -0,0 +1,69 @@
+package command_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"hookt.dev/cmd/pkg/command"
+)
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{
+		"a.yaml",
+		"b.yml",
+		"c.txt",
+		"sub/d.yaml",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		args []string
+		want []string
+	}{
+		{
+			[]string{dir},
+			[]string{"a.yaml", "b.yml", "sub/d.yaml"},
+		},
+		{
+			[]string{filepath.Join(dir, "*.y*ml"), filepath.Join(dir, "a.yaml")},
+			[]string{"a.yaml", "b.yml"},
+		},
+		{
+			[]string{filepath.Join(dir, "c.txt"), filepath.Join(dir, "sub")},
+			[]string{"c.txt", "sub/d.yaml"},
+		},
+	}
+
+	for _, cas := range cases {
+		t.Run("", func(t *testing.T) {
+			files, err := command.Files(cas.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, file := range files {
+				files[i], _ = filepath.Rel(dir, file)
+				files[i] = filepath.ToSlash(files[i])
+			}
+
+			if !slices.Equal(files, cas.want) {
+				t.Errorf("Files()=%v, want %v", files, cas.want)
+			}
+		})
+	}
+
+	if _, err := command.Files([]string{filepath.Join(dir, "missing.yaml")}); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,43 @@
+package command
+
+import (
+	"context"
+	"os"
+
+	"hookt.dev/cmd/pkg/errors"
+)
+
+// ValidateFiles checks every workflow file without running
+// it and summarizes the problems found.
+func (app *App) ValidateFiles(ctx context.Context, files []string) *Summary {
+	sum := &Summary{
+		Files: make([]*File, len(files)),
+	}
+
+	for i, path := range files {
+		f := &File{Path: path}
+		sum.Files[i] = f
+
+		if err := app.validateFile(ctx, f); err != nil {
+			f.Status = "invalid"
+			f.Error = err.Error()
+			f.err = err
+			sum.Failed++
+			continue
+		}
+
+		f.Status = "valid"
+		sum.Passed++
+	}
+
+	return sum
+}
+
+func (app *App) validateFile(ctx context.Context, f *File) error {
+	p, err := os.ReadFile(f.Path)
+	if err != nil {
+		return errors.New("failed to read file: %w", err)
+	}
+
+	return app.Engine.Validate(ctx, p)
+}
@@ -0,0 +1,44 @@
+package command
+
+import (
+	"os"
+	"strings"
+
+	"hookt.dev/cmd/pkg/errors"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Vars reads the variables of the YAML files, in order, then sets
+// the key=value pairs of vars, which take precedence.
+func Vars(files, vars []string) (map[string]any, error) {
+	m := make(map[string]any)
+
+	for _, file := range files {
+		p, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.New("failed to read var file: %w", err)
+		}
+
+		var v map[string]any
+
+		if err := yaml.Unmarshal(p, &v); err != nil {
+			return nil, errors.New("failed to parse var file %q: %w", file, err)
+		}
+
+		for key, value := range v {
+			m[key] = value
+		}
+	}
+
+	for _, kv := range vars {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return nil, errors.New("invalid var %q: expected key=value", kv)
+		}
+
+		m[key] = value
+	}
+
+	return m, nil
+}
@@ -0,0 +1,74 @@
+package command_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"hookt.dev/cmd/pkg/command"
+
+	"github.com/spf13/pflag"
+)
+
+func TestVars(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "vars.yaml")
+
+	if err := os.WriteFile(file, []byte("url: http://staging\nretries: 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := command.Vars([]string{file}, []string{"url=http://dev", "token=a=b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{
+		"url":     "http://dev",
+		"retries": float64(3),
+		"token":   "a=b",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Vars()=%v, want %v", got, want)
+	}
+
+	if _, err := command.Vars(nil, []string{"novalue"}); err == nil {
+		t.Error("expected error for var without value")
+	}
+}
+
+func TestInitVars(t *testing.T) {
+	app := command.New("hkt")
+	if app.Engine == nil {
+		t.Fatal("New().Engine=nil, want engine")
+	}
+
+	f := pflag.NewFlagSet("hkt", pflag.ContinueOnError)
+	app.RegisterVars(f)
+
+	if err := f.Parse([]string{"--var", "token=secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	const workflow = `
+inputs:
+  token:
+    required: true
+jobs:
+  - steps: []
+`
+
+	if err := app.Engine.Validate(context.Background(), []byte(workflow)); err == nil {
+		t.Fatal("Validate()=nil before Init, want required input error")
+	}
+
+	if err := app.Init(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.Engine.Validate(context.Background(), []byte(workflow)); err != nil {
+		t.Fatalf("Validate()=%v after Init", err)
+	}
+}
@@ -4,42 +4,91 @@ import (
 	"context"
 	"log/slog"
 	"strconv"
+	"time"
 
 	"hookt.dev/cmd/pkg/check"
 	"hookt.dev/cmd/pkg/errors"
 	"hookt.dev/cmd/pkg/plugin/builtin"
+	"hookt.dev/cmd/pkg/plugin/external"
 	"hookt.dev/cmd/pkg/proto"
+	"hookt.dev/cmd/pkg/schema"
 	"hookt.dev/cmd/pkg/trace"
 
 	"github.com/lmittmann/tint"
 	"golang.org/x/sync/errgroup"
 )
 
-var plugins []proto.Interface
-
-func init() {
-	for _, p := range builtin.Plugins() {
-		plugins = append(plugins, p)
-	}
-}
-
 type Engine struct {
-	p *proto.P
+	p   *proto.P
+	err error
+
+	builtins bool
+	plugins  []proto.Interface
+	popts    []func(*proto.P)
 }
 
 func New(opts ...func(*Engine)) *Engine {
 	ngn := &Engine{
-		p: proto.New(
-			proto.WithPlugins(plugins...),
-		),
+		builtins: true,
 	}
 	for _, opt := range opts {
 		opt(ngn)
 	}
+
+	var (
+		reg     = make(proto.Registry)
+		plugins []proto.Interface
+	)
+
+	if ngn.builtins {
+		for _, p := range builtin.Plugins() {
+			plugins = append(plugins, p)
+		}
+	}
+
+	if err := reg.Register(append(plugins, ngn.plugins...)...); err != nil {
+		ngn.err = errors.New("failed to register plugins: %w", err)
+	}
+
+	ngn.p = proto.New(append([]func(*proto.P){
+		proto.WithRegistry(reg),
+		proto.WithResolver(external.Resolve),
+	}, ngn.popts...)...)
+
 	return ngn
 }
 
+// Plugins lists the plugins registered with the engine.
+// WithVars overrides variables and inputs of the workflows the engine
+// runs or validates from then on, like the WithVars option.
+func (e *Engine) WithVars(vars map[string]any) *Engine {
+	proto.WithVars(vars)(e.p)
+	return e
+}
+
+func (e *Engine) Plugins() []proto.Info {
+	return e.p.Plugins()
+}
+
+// Schema returns the JSON Schema of workflows run by the engine.
+func (e *Engine) Schema() *schema.Schema {
+	return e.p.Schema()
+}
+
+// Validate checks the workflow without running it.
+func (e *Engine) Validate(ctx context.Context, p []byte) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	return e.p.Validate(ctx, p)
+}
+
 func (e *Engine) Run(ctx context.Context, p []byte) (*check.S, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+
 	var s check.S
 
 	ctx = trace.WithPattern(ctx, trace.ContextPattern(ctx).Join(s.Trace()))
@@ -49,39 +98,52 @@ func (e *Engine) Run(ctx context.Context, p []byte) (*check.S, error) {
 		return nil, errors.New("failed to parse file: %w", err)
 	}
 
-	var g errgroup.Group
+	defer w.Close()
+
+	if err := w.Start(ctx); err != nil {
+		return nil, errors.New("failed to start workflow: %w", err)
+	}
+
+	var (
+		g     errgroup.Group
+		needs = make(map[string]*gate, len(w.Jobs))
+	)
+
+	for _, job := range w.Jobs {
+		needs[job.ID] = &gate{done: make(chan struct{})}
+	}
 
 	for i, job := range w.Jobs {
 		ctx := trace.With(ctx, "job", job.ID)
 		ctx = trace.With(ctx, "job-index", strconv.Itoa(i))
-		for j, step := range job.Steps {
-			ctx := trace.With(ctx, "step", step.ID)
-			ctx = trace.With(ctx, "step-desc", step.Desc)
-			ctx = trace.With(ctx, "step-index", strconv.Itoa(j))
-			g.Go(func() error {
-				r, ok := step.With.(proto.Runner)
-				if !ok {
-					return errors.New("step %q does not implement proto.Runner", step.ID)
-				}
-
-				defer r.Stop(ctx)
+		g.Go(func() (err error) {
+			defer func() { needs[job.ID].close(err == nil) }()
 
-				if err := r.Run(ctx, &s); err != nil {
-					slog.Error("step failure",
-						"desc", step.Desc,
-						tint.Err(err),
+			defer func() {
+				if e := job.Close(ctx); e != nil {
+					slog.Warn("job close",
+						"job", job.ID,
+						tint.Err(e),
 					)
+				}
+			}()
 
-					return err
+			if err := ready(ctx, &job, needs); err != nil {
+				for j, step := range job.Steps {
+					s.Skip(s.Begin(record(i, &job, &step, j)), err.Error())
+					job.Finish(&step, err)
 				}
 
-				slog.Info("step pass",
-					"desc", step.Desc,
+				slog.Warn("job skipped",
+					"job", job.ID,
+					tint.Err(err),
 				)
 
-				return nil
-			})
-		}
+				return err
+			}
+
+			return runJob(ctx, i, &job, &s)
+		})
 	}
 
 	done := make(chan error)
@@ -97,3 +159,154 @@ func (e *Engine) Run(ctx context.Context, p []byte) (*check.S, error) {
 		return &s, err
 	}
 }
+
+func runJob(ctx context.Context, i int, job *proto.Job, s *check.S) error {
+	var (
+		g     errgroup.Group
+		gates = make(map[string]*gate, len(job.Steps))
+	)
+
+	for _, step := range job.Steps {
+		gates[step.ID] = &gate{done: make(chan struct{})}
+	}
+
+	for j, step := range job.Steps {
+		ctx := trace.With(ctx, "step", step.ID)
+		ctx = trace.With(ctx, "step-desc", step.Desc)
+		ctx = trace.With(ctx, "step-index", strconv.Itoa(j))
+		g.Go(func() (err error) {
+			defer func() { gates[step.ID].close(err == nil) }()
+
+			rec := s.Begin(record(i, job, &step, j))
+
+			if err := wait(ctx, &step, gates); err != nil {
+				s.Skip(rec, err.Error())
+				job.Finish(&step, err)
+
+				slog.Error("step skipped",
+					"desc", step.Desc,
+					tint.Err(err),
+				)
+
+				return err
+			}
+
+			err = run(ctx, &step, s)
+			s.Finish(rec, err)
+			job.Finish(&step, err)
+
+			if err != nil {
+				slog.Error("step failure",
+					"desc", step.Desc,
+					tint.Err(err),
+				)
+
+				return err
+			}
+
+			slog.Info("step pass",
+				"desc", step.Desc,
+			)
+
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
+
+func record(i int, job *proto.Job, step *proto.Step, index int) *check.Record {
+	return &check.Record{
+		Job:      job.ID,
+		JobIndex: i,
+		Step:     step.ID,
+		Plugin:   step.Uses,
+		Desc:     step.Desc,
+		Index:    index,
+	}
+}
+
+// gate is closed once a job or a step finishes, ok reports
+// whether it passed.
+type gate struct {
+	done chan struct{}
+	ok   bool
+}
+
+func (g *gate) close(ok bool) {
+	g.ok = ok
+	close(g.done)
+}
+
+// ready blocks until every job the job needs has passed.
+func ready(ctx context.Context, job *proto.Job, needs map[string]*gate) error {
+	for _, id := range job.Needs {
+		g := needs[id]
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-g.done:
+		}
+
+		if !g.ok {
+			return errors.New("job %q did not pass", id)
+		}
+	}
+
+	return nil
+}
+
+// wait blocks until the step is ready to run, which is after
+// its wait_for step has passed and the defer delay has elapsed.
+func wait(ctx context.Context, step *proto.Step, gates map[string]*gate) error {
+	if step.WaitFor != "" {
+		g := gates[step.WaitFor]
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-g.done:
+		}
+
+		if !g.ok {
+			return errors.New("step %q did not pass", step.WaitFor)
+		}
+	}
+
+	if step.Defer > 0 {
+		t := time.NewTimer(step.Defer)
+		defer t.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+
+	return nil
+}
+
+func run(ctx context.Context, step *proto.Step, s *check.S) error {
+	r, ok := step.With.(proto.Runner)
+	if !ok {
+		return errors.New("step %q does not implement proto.Runner", step.ID)
+	}
+
+	defer r.Stop(ctx)
+
+	if step.Timeout <= 0 {
+		return r.Run(ctx, s)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, step.Timeout)
+	defer cancel()
+
+	err := r.Run(ctx, s)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return errors.New("step %q has %w after %v: %w", step.ID, check.ErrTimeout, step.Timeout, err)
+	}
+
+	return err
+}
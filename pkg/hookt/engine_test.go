@@ -0,0 +1,632 @@
+package hookt_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"hookt.dev/cmd/pkg/check"
+	"hookt.dev/cmd/pkg/hookt"
+	"hookt.dev/cmd/pkg/internal/testutil"
+	"hookt.dev/cmd/pkg/proto"
+)
+
+const plugins = `
+jobs:
+  - id: steps
+    plugins:
+      - id: hook
+        uses: webhook
+        with:
+          endpoints:
+            /ready: ${{ setvar "webhook-url" . }}
+          do:
+            body: "{}"
+      - uses: event
+        with:
+          sources:
+          - hook
+      - uses: http
+        with:
+          timeout: 5s
+    steps:
+`
+
+func TestRunStep(t *testing.T) {
+	cases := map[string]struct {
+		steps string
+		err   string
+		min   time.Duration
+	}{
+		"defer": {
+			steps: `
+      - uses: http
+        defer: 200ms
+        with:
+          request:
+            url: ${{ var "webhook-url" }}
+          response:
+            pass:
+              .status: 200
+`,
+			min: 200 * time.Millisecond,
+		},
+		"wait_for": {
+			steps: `
+      - uses: http
+        wait_for: first
+        with:
+          request:
+            url: ${{ var "webhook-url" }}
+          response:
+            pass:
+              .status: 200
+      - uses: http
+        id: first
+        defer: 200ms
+        with:
+          request:
+            url: ${{ var "webhook-url" }}
+          response:
+            pass:
+              .status: 200
+`,
+			min: 200 * time.Millisecond,
+		},
+		"timeout": {
+			steps: `
+      - uses: event
+        timeout: 100ms
+        with:
+          match:
+            .path: /never
+`,
+			err: "timed out after 100ms",
+		},
+		"wait_for failed": {
+			steps: `
+      - uses: event
+        id: never
+        timeout: 100ms
+        with:
+          match:
+            .path: /never
+      - uses: http
+        wait_for: never
+        with:
+          request:
+            url: ${{ var "webhook-url" }}
+`,
+			err: `step "never"`,
+		},
+	}
+
+	for name, cas := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			start := time.Now()
+
+			_, err := hookt.New().Run(ctx, []byte(plugins+cas.steps))
+			if cas.err == "" && err != nil {
+				t.Fatalf("Run()=%+v", err)
+			}
+			if cas.err != "" && (err == nil || !strings.Contains(err.Error(), cas.err)) {
+				t.Fatalf("Run()=%v, want error containing %q", err, cas.err)
+			}
+
+			if d := time.Since(start); d < cas.min {
+				t.Errorf("Run() took %v, want at least %v", d, cas.min)
+			}
+		})
+	}
+}
+
+func TestParseWaitFor(t *testing.T) {
+	cases := map[string]string{
+		"unknown": `
+      - uses: http
+        wait_for: missing
+        with:
+          request:
+            url: http://127.0.0.1
+`,
+		"cycle": `
+      - uses: http
+        id: a
+        wait_for: b
+        with:
+          request:
+            url: http://127.0.0.1
+      - uses: http
+        id: b
+        wait_for: a
+        with:
+          request:
+            url: http://127.0.0.1
+`,
+		"duration": `
+      - uses: http
+        timeout: soon
+        with:
+          request:
+            url: http://127.0.0.1
+`,
+	}
+
+	for name, steps := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := hookt.New().Run(context.Background(), []byte(plugins+steps)); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+const jobs = `
+jobs:
+  - id: second
+    needs:
+    - first
+    plugins:
+      - uses: webhook
+        with:
+          endpoints:
+            /ready: ${{ setvar "second-url" . }}
+          do:
+            body: "{}"
+      - uses: http
+        with:
+          timeout: 5s
+    steps:
+      - uses: http
+        with:
+          request:
+            url: ${{ var "second-url" }}
+          response:
+            pass:
+              .status: 200
+  - id: first
+    plugins:
+      - uses: webhook
+        with:
+          endpoints:
+            /ready: ${{ setvar "first-url" . }}
+          do:
+            body: "{}"
+    steps:
+      - uses: http
+        defer: 200ms
+        with:
+          request:
+            url: ${{ var "first-url" }}
+          response:
+            pass:
+              .status: 200
+  - id: broken
+    steps:
+      - uses: event
+        timeout: 100ms
+        with:
+          match:
+            .path: /never
+  - id: skipped
+    needs:
+    - broken
+    steps:
+      - uses: event
+        with:
+          match:
+            .path: /never
+`
+
+func TestRunJobs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+
+	s, err := hookt.New().Run(ctx, []byte(jobs))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if d := time.Since(start); d < 200*time.Millisecond {
+		t.Errorf("Run() took %v, want at least 200ms", d)
+	}
+
+	want := map[string]check.Status{
+		"first":   check.StatusPass,
+		"second":  check.StatusPass,
+		"broken":  check.StatusTimeout,
+		"skipped": check.StatusSkip,
+	}
+
+	end := make(map[string]time.Time)
+
+	for _, r := range s.Records {
+		if r.Status != want[r.Job] {
+			t.Errorf("%s/%s: got status %q, want %q", r.Job, r.Step, r.Status, want[r.Job])
+		}
+		end[r.Job] = r.End
+	}
+
+	for _, r := range s.Records {
+		if r.Job == "second" && r.Start.Before(end["first"]) {
+			t.Errorf("second started at %v, before first ended at %v", r.Start, end["first"])
+		}
+	}
+
+	if res := s.Results(); len(res) != 2 {
+		t.Errorf("Results()=%+v, want timeout and skip", res)
+	}
+}
+
+func TestParseNeeds(t *testing.T) {
+	cases := map[string]string{
+		"unknown": `
+jobs:
+  - id: a
+    needs: [missing]
+    steps: []
+`,
+		"cycle": `
+jobs:
+  - id: a
+    needs: [c]
+    steps: []
+  - id: b
+    needs: [a]
+    steps: []
+  - id: c
+    needs: [b]
+    steps: []
+`,
+	}
+
+	for name, workflow := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := hookt.New().Run(context.Background(), []byte(workflow)); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestWithPlugin(t *testing.T) {
+	const workflow = `
+jobs:
+  - id: stub
+    plugins:
+      - uses: stub
+        with:
+          count: true
+    steps:
+      - uses: stub
+        with:
+          count: true
+`
+
+	var (
+		p   = testutil.NewPlugin("stub")
+		ngn = hookt.New(hookt.WithoutBuiltins(), hookt.WithPlugin(p))
+	)
+
+	if got := ngn.Plugins(); len(got) != 1 || got[0].Name != "stub" || got[0].Description == "" {
+		t.Fatalf("Plugins()=%+v, want stub only", got)
+	}
+
+	if _, err := ngn.Run(context.Background(), []byte(workflow)); err != nil {
+		t.Fatalf("Run()=%+v", err)
+	}
+
+	if n := p.Ran(); n != 1 {
+		t.Errorf("ran %d steps, want 1", n)
+	}
+
+	if _, err := ngn.Run(context.Background(), []byte(plugins)); err == nil {
+		t.Error("Run()=nil, want error for builtin plugins")
+	}
+}
+
+func TestWithPluginDuplicate(t *testing.T) {
+	cases := map[string]*hookt.Engine{
+		"builtin": hookt.New(hookt.WithPlugin(testutil.NewPlugin("http"))),
+		"twice":   hookt.New(hookt.WithoutBuiltins(), hookt.WithPlugin(testutil.NewPlugin("stub"), testutil.NewPlugin("stub"))),
+	}
+
+	for name, ngn := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ngn.Run(context.Background(), []byte(plugins))
+			if err == nil || !strings.Contains(err.Error(), "already registered") {
+				t.Fatalf("Run()=%v, want already registered error", err)
+			}
+		})
+	}
+}
+
+func TestRunInputs(t *testing.T) {
+	const workflow = `
+vars:
+  scheme: Bearer
+inputs:
+  status:
+    type: number
+    default: 200
+  token:
+    required: true
+jobs:
+  - id: inputs
+    plugins:
+      - uses: webhook
+        with:
+          endpoints:
+            /ready: ${{ setvar "url" . }}
+          do:
+            body: "{}"
+      - uses: http
+        with:
+          timeout: 5s
+    steps:
+      - uses: http
+        with:
+          request:
+            url: ${{ var "url" }}
+            headers:
+              Authorization: ${{ var "scheme" }} ${{ var "token" }}
+          response:
+            pass:
+              .status: ${{ var "status" }}
+`
+
+	cases := map[string]struct {
+		vars map[string]any
+		err  string
+	}{
+		"default": {
+			vars: map[string]any{"token": "secret"},
+		},
+		"override": {
+			vars: map[string]any{"token": "secret", "status": "200"},
+		},
+		"string": {
+			vars: map[string]any{"token": float64(1234), "status": float64(200)},
+		},
+		"required": {
+			err: `input "token": is required`,
+		},
+		"type": {
+			vars: map[string]any{"token": "secret", "status": "ok"},
+			err:  `input "status": expected number`,
+		},
+		"mismatch": {
+			vars: map[string]any{"token": "secret", "status": "404"},
+			err:  "did not match",
+		},
+	}
+
+	for name, cas := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			_, err := hookt.New(hookt.WithVars(cas.vars)).Run(ctx, []byte(workflow))
+			if cas.err == "" && err != nil {
+				t.Fatalf("Run()=%+v", err)
+			}
+			if cas.err != "" && (err == nil || !strings.Contains(err.Error(), cas.err)) {
+				t.Fatalf("Run()=%v, want error containing %q", err, cas.err)
+			}
+		})
+	}
+}
+
+func TestRunVarTimeout(t *testing.T) {
+	const workflow = `
+jobs:
+  - id: vars
+    plugins:
+      - uses: webhook
+        with:
+          endpoints:
+            /ready: ${{ setvar "url" . }}
+          do:
+            body: "{}"
+      - uses: http
+        with:
+          timeout: 5s
+    steps:
+      - uses: http
+        %s
+        with:
+          request:
+            url: ${{ var "late-url" }}
+          response:
+            pass:
+              .status: 200
+      - uses: http
+        defer: 300ms
+        with:
+          request:
+            url: ${{ setvar "late-url" (var "url") }}
+`
+
+	cases := map[string]struct {
+		timeout string
+		err     string
+	}{
+		"step timeout": {
+			timeout: "timeout: 5s",
+		},
+		"var timeout": {
+			err: `variable "late-url" is not set`,
+		},
+	}
+
+	for name, cas := range cases {
+		t.Run(name, func(t *testing.T) {
+			ngn := hookt.New(hookt.WithProtoOptions(proto.WithVarTimeout(100 * time.Millisecond)))
+
+			_, err := ngn.Run(context.Background(), []byte(fmt.Sprintf(workflow, cas.timeout)))
+			if cas.err == "" && err != nil {
+				t.Fatalf("Run()=%+v", err)
+			}
+			if cas.err != "" && (err == nil || !strings.Contains(err.Error(), cas.err)) {
+				t.Fatalf("Run()=%v, want error containing %q", err, cas.err)
+			}
+		})
+	}
+}
+
+func TestRunOutputs(t *testing.T) {
+	const workflow = `
+jobs:
+  - id: outputs
+    plugins:
+      - id: hook
+        uses: webhook
+        with:
+          endpoints:
+            /resources: ${{ setvar "url" . }}
+          do:
+            body: '{"id": "abc"}'
+      - uses: event
+        with:
+          sources:
+          - hook
+      - uses: http
+        with:
+          timeout: 5s
+    steps:
+      - uses: http
+        id: create
+        with:
+          request:
+            url: ${{ var "url" }}
+            method: POST
+            body: "{}"
+      - uses: http
+        id: notify
+        wait_for: create
+        with:
+          request:
+            url: ${{ var "url" }}
+            method: POST
+            body: '{"ref": "${{ (step "create").body.id }}"}'
+      - uses: event
+        id: received
+        timeout: 5s
+        with:
+          match:
+            .body.ref: abc
+      - uses: http
+        wait_for: received
+        with:
+          request:
+            url: ${{ var "url" }}?ref=${{ (step "received").body.ref }}
+            method: POST
+            body: "{}"
+          response:
+            pass:
+              .status: 200
+      - uses: http
+        with:
+          request:
+            url: ${{ (step "missing").body.id }}
+`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s, err := hookt.New().Run(ctx, []byte(workflow))
+	if err == nil || !strings.Contains(err.Error(), `step "missing" is not in job "outputs", known steps: [create, notify, received, #step-3, #step-4]`) {
+		t.Fatalf("Run()=%v, want error for missing step", err)
+	}
+
+	if res := s.Results(); len(res) != 1 {
+		t.Errorf("Results()=%+v, want missing step only", res)
+	}
+}
+
+func TestRunExport(t *testing.T) {
+	const workflow = `
+jobs:
+  - id: create
+    plugins:
+      - id: hook
+        uses: webhook
+        with:
+          endpoints:
+            /resources: ${{ setvar "url" . }}
+          do:
+            body: "{}"
+      - uses: event
+        with:
+          sources:
+          - hook
+      - uses: http
+        with:
+          timeout: 5s
+    steps:
+      - uses: http
+        with:
+          request:
+            url: ${{ var "url" }}
+            method: POST
+            body: '{"id": "xyz", "tags": ["a", "b"]}'
+      - uses: event
+        timeout: 5s
+        with:
+          match:
+            .method: POST
+          export:
+            .body.id: resource-id
+            .body.tags | length: tags
+  - id: check
+    needs: [create]
+    plugins:
+      - id: hook
+        uses: webhook
+        with:
+          endpoints:
+            /check: ${{ setvar "check-url" . }}
+          do:
+            body: "{}"
+      - uses: event
+        with:
+          sources:
+          - hook
+      - uses: http
+        with:
+          timeout: 5s
+    steps:
+      - uses: http
+        with:
+          request:
+            url: ${{ var "check-url" }}
+            method: POST
+            body: '{"id": "${{ var "resource-id" }}", "tags": ${{ var "tags" }}}'
+      - uses: event
+        timeout: 5s
+        with:
+          match:
+            .body.id: xyz
+            .body.tags: 2
+`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := hookt.New().Run(ctx, []byte(workflow)); err != nil {
+		t.Fatalf("Run()=%+v", err)
+	}
+
+	invalid := strings.Replace(workflow, ".body.tags | length: tags", ".body.tags |: tags", 1)
+
+	if err := hookt.New().Validate(ctx, []byte(invalid)); err == nil || !strings.Contains(err.Error(), "failed to parse jq") {
+		t.Fatalf("Validate()=%v, want jq error", err)
+	}
+}
@@ -4,6 +4,29 @@ import "hookt.dev/cmd/pkg/proto"
 
 func WithProtoOptions(opts ...func(*proto.P)) func(*Engine) {
 	return func(e *Engine) {
-		e.p = e.p.With(opts...)
+		e.popts = append(e.popts, opts...)
 	}
 }
+
+// WithPlugin registers plugins with the engine. Registering a plugin
+// under a name that is already taken, builtin or not, makes the engine
+// fail to run and validate workflows.
+func WithPlugin(plugins ...proto.Interface) func(*Engine) {
+	return func(e *Engine) {
+		e.plugins = append(e.plugins, plugins...)
+	}
+}
+
+// WithoutBuiltins leaves out the builtin plugins, so that
+// only the plugins registered with WithPlugin are available.
+func WithoutBuiltins() func(*Engine) {
+	return func(e *Engine) {
+		e.builtins = false
+	}
+}
+
+// WithVars sets variables readable with the var template function,
+// overriding the vars and inputs declared by workflows.
+func WithVars(vars map[string]any) func(*Engine) {
+	return WithProtoOptions(proto.WithVars(vars))
+}
@@ -0,0 +1,218 @@
+// Package hookttest runs workflows from Go tests.
+//
+//	func TestScenario(t *testing.T) {
+//		hookttest.Run(t, "testdata/scenario.yaml",
+//			hookttest.WithVar("base-url", srv.URL),
+//		)
+//	}
+//
+// Every job of the workflow becomes a subtest of t, and every step a
+// subtest of its job, which fails with the patterns that did not match.
+package hookttest // import "hookt.dev/cmd/pkg/hookttest"
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"hookt.dev/cmd/pkg/check"
+	"hookt.dev/cmd/pkg/hookt"
+	"hookt.dev/cmd/pkg/proto"
+	"hookt.dev/cmd/pkg/trace"
+)
+
+type config struct {
+	vars    map[string]any
+	plugins []proto.Interface
+	opts    []func(*hookt.Engine)
+	trace   bool
+}
+
+// Run runs the workflow file, failing t when it does not pass, and
+// returns the recorded steps for further checks.
+func Run(t *testing.T, file string, opts ...Option) *check.S {
+	t.Helper()
+
+	p, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read workflow: %v", err)
+	}
+
+	return RunBytes(t, p, opts...)
+}
+
+// RunBytes is like Run, reading the workflow from p.
+func RunBytes(t *testing.T, p []byte, opts ...Option) *check.S {
+	t.Helper()
+
+	cfg := config{trace: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	ngn := hookt.New(append([]func(*hookt.Engine){
+		hookt.WithPlugin(cfg.plugins...),
+		hookt.WithProtoOptions(proto.WithVars(cfg.vars)),
+	}, cfg.opts...)...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if deadline, ok := t.Deadline(); ok {
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	w := &writer{t: t}
+
+	if cfg.trace {
+		l := slog.New(slog.NewTextHandler(w, nil))
+
+		ctx = trace.WithPattern(ctx, trace.LogPatternTo(l))
+		ctx = trace.WithSchedule(ctx, trace.LogScheduleTo(l))
+	}
+
+	s, err := ngn.Run(ctx, p)
+	w.close()
+
+	if s == nil {
+		t.Fatalf("failed to run workflow: %v", err)
+	}
+
+	if !Report(testingT{t}, s) && err != nil {
+		t.Errorf("workflow failed: %v", err)
+	}
+
+	return s
+}
+
+// Reporter is the part of testing.T the recorded steps
+// of a workflow are reported to.
+type Reporter interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Skip(args ...any)
+	Run(name string, f func(Reporter)) bool
+}
+
+// testingT reports to a testing.T.
+type testingT struct {
+	*testing.T
+}
+
+func (t testingT) Run(name string, f func(Reporter)) bool {
+	return t.T.Run(name, func(t *testing.T) {
+		f(testingT{t})
+	})
+}
+
+// Report runs a subtest of t for every job and step recorded in s,
+// failing the steps that did not pass and skipping the skipped ones.
+// It reports whether any step failed.
+func Report(t Reporter, s *check.S) bool {
+	t.Helper()
+
+	var (
+		jobs    []string
+		records = make(map[string][]*check.Record)
+		failed  bool
+	)
+
+	for _, r := range s.Records {
+		if _, ok := records[r.Job]; !ok {
+			jobs = append(jobs, r.Job)
+		}
+		records[r.Job] = append(records[r.Job], r)
+	}
+
+	for _, job := range jobs {
+		slices.SortFunc(records[job], func(a, b *check.Record) int {
+			return a.Index - b.Index
+		})
+
+		t.Run(job, func(t Reporter) {
+			for _, r := range records[job] {
+				if r.Status != check.StatusPass && r.Status != check.StatusSkip {
+					failed = true
+				}
+
+				t.Run(name(r), func(t Reporter) {
+					step(t, r)
+				})
+			}
+		})
+	}
+
+	return failed
+}
+
+func name(r *check.Record) string {
+	if r.Desc != "" {
+		return r.Desc
+	}
+	return strings.TrimPrefix(r.Step, "#")
+}
+
+func step(t Reporter, r *check.Record) {
+	t.Helper()
+
+	switch r.Status {
+	case check.StatusPass:
+	case check.StatusSkip:
+		t.Skip(r.Error)
+	default:
+		failures := r.Failures()
+
+		for _, f := range failures {
+			if f.Error != "" {
+				t.Errorf("%s: %s (got %s)", f.Key, f.Error, format(f.Got))
+				continue
+			}
+
+			t.Errorf("%s: got %s, expected %s", f.Key, format(f.Got), format(f.Expected))
+		}
+
+		if len(failures) == 0 {
+			t.Errorf("%s: %s", r.Status, r.Error)
+		}
+	}
+}
+
+func format(v any) string {
+	p, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(p)
+}
+
+// writer writes trace logs to t, dropping the ones
+// written by plugins after the workflow completed.
+type writer struct {
+	mu   sync.Mutex
+	t    *testing.T
+	done bool
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.done {
+		w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+
+	return len(p), nil
+}
+
+func (w *writer) close() {
+	w.mu.Lock()
+	w.done = true
+	w.mu.Unlock()
+}
@@ -0,0 +1,132 @@
+package hookttest_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"hookt.dev/cmd/pkg/hookt"
+	"hookt.dev/cmd/pkg/hookttest"
+	"hookt.dev/cmd/pkg/internal/testutil"
+)
+
+func TestRun(t *testing.T) {
+	s := hookttest.Run(t, "testdata/echo.yaml",
+		hookttest.WithVar("greeting", "hello"),
+	)
+
+	if len(s.Records) != 2 {
+		t.Fatalf("got %d records, want 2", len(s.Records))
+	}
+}
+
+func TestRunPlugin(t *testing.T) {
+	const workflow = `
+jobs:
+  - id: count
+    plugins:
+      - uses: counter
+        with:
+          start: 0
+    steps:
+      - uses: counter
+        with:
+          by: 1
+      - uses: counter
+        with:
+          by: 1
+`
+
+	p := testutil.NewPlugin("counter")
+
+	hookttest.RunBytes(t, []byte(workflow), hookttest.WithPlugin(p))
+
+	if n := p.Ran(); n != 2 {
+		t.Errorf("counted %d steps, want 2", n)
+	}
+}
+
+// recorder is a hookttest.Reporter recording what is reported to it.
+type recorder struct {
+	name    string
+	errors  []string
+	skipped string
+	subs    []*recorder
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Skip(args ...any) {
+	r.skipped = fmt.Sprint(args...)
+}
+
+func (r *recorder) Run(name string, f func(hookttest.Reporter)) bool {
+	sub := &recorder{name: name}
+	r.subs = append(r.subs, sub)
+	f(sub)
+	return len(sub.errors) == 0
+}
+
+func TestReport(t *testing.T) {
+	const workflow = `
+jobs:
+  - id: count
+    plugins:
+      - uses: counter
+        with:
+          start: 0
+    steps:
+      - uses: counter
+        id: first
+        with:
+          by: 1
+      - uses: counter
+        desc: second step
+        with:
+          fail: boom
+      - uses: counter
+        id: third
+        wait_for: "#step-1"
+        with:
+          by: 1
+`
+
+	ngn := hookt.New(hookt.WithPlugin(testutil.NewPlugin("counter")))
+
+	s, err := ngn.Run(context.Background(), []byte(workflow))
+	if err == nil {
+		t.Fatal("Run()=nil, want error")
+	}
+
+	var r recorder
+
+	if !hookttest.Report(&r, s) {
+		t.Error("Report()=false, want true")
+	}
+
+	if len(r.subs) != 1 || r.subs[0].name != "count" {
+		t.Fatalf("got jobs %+v, want count", r.subs)
+	}
+
+	steps := r.subs[0].subs
+	if len(steps) != 3 {
+		t.Fatalf("got %d steps, want 3", len(steps))
+	}
+
+	if first := steps[0]; first.name != "first" || len(first.errors) != 0 || first.skipped != "" {
+		t.Errorf("first step=%+v, want passed", first)
+	}
+
+	if second := steps[1]; second.name != "second step" || len(second.errors) != 1 || !strings.Contains(second.errors[0], "boom") {
+		t.Errorf("second step=%+v, want failed with boom", second)
+	}
+
+	if third := steps[2]; third.name != "third" || len(third.errors) != 0 || !strings.Contains(third.skipped, `step "#step-1" did not pass`) {
+		t.Errorf("third step=%+v, want skipped", third)
+	}
+}
@@ -0,0 +1,40 @@
+package hookttest
+
+import (
+	"hookt.dev/cmd/pkg/hookt"
+	"hookt.dev/cmd/pkg/proto"
+)
+
+type Option func(*config)
+
+// WithVar sets a variable readable with the var template function.
+func WithVar(name string, value any) Option {
+	return func(c *config) {
+		if c.vars == nil {
+			c.vars = make(map[string]any)
+		}
+		c.vars[name] = value
+	}
+}
+
+// WithPlugin registers in-memory plugins with the engine.
+func WithPlugin(plugins ...proto.Interface) Option {
+	return func(c *config) {
+		c.plugins = append(c.plugins, plugins...)
+	}
+}
+
+// WithEngineOptions passes options to the engine running the workflow.
+func WithEngineOptions(opts ...func(*hookt.Engine)) Option {
+	return func(c *config) {
+		c.opts = append(c.opts, opts...)
+	}
+}
+
+// WithoutTrace stops logging the matching of patterns and the
+// scheduling of messages to the test log.
+func WithoutTrace() Option {
+	return func(c *config) {
+		c.trace = false
+	}
+}
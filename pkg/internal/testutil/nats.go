@@ -0,0 +1,34 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+// NATSServer starts an embedded NATS server, shut down when the
+// test ends, and returns its client URL.
+func NATSServer(t testing.TB) string {
+	t.Helper()
+
+	ns, err := server.NewServer(&server.Options{
+		Host:   "127.0.0.1",
+		Port:   server.RANDOM_PORT,
+		NoLog:  true,
+		NoSigs: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go ns.Start()
+
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats-server is not ready")
+	}
+
+	t.Cleanup(ns.Shutdown)
+
+	return ns.ClientURL()
+}
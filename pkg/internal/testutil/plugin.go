@@ -0,0 +1,52 @@
+// Package testutil holds the helpers shared by the tests of
+// several packages.
+package testutil // import "hookt.dev/cmd/pkg/internal/testutil"
+
+import (
+	"context"
+	"sync/atomic"
+
+	"hookt.dev/cmd/pkg/check"
+	"hookt.dev/cmd/pkg/errors"
+	"hookt.dev/cmd/pkg/proto"
+)
+
+// Plugin is an in-memory plugin counting the steps it runs. Its steps
+// pass, unless their with object sets a fail reason.
+type Plugin struct {
+	name string
+	ran  atomic.Int64
+}
+
+func NewPlugin(name string) *Plugin {
+	return &Plugin{name: name}
+}
+
+func (p *Plugin) Name() string                           { return p.name }
+func (p *Plugin) Description() string                    { return "Counts the steps it runs" }
+func (p *Plugin) Plugin(context.Context, *proto.P) any   { return p }
+func (p *Plugin) Init(context.Context, *proto.Job) error { return nil }
+func (p *Plugin) Step(context.Context) any               { return &Step{p: p} }
+
+// Ran returns the number of steps run so far.
+func (p *Plugin) Ran() int {
+	return int(p.ran.Load())
+}
+
+type Step struct {
+	Fail string `json:"fail,omitempty"`
+
+	p *Plugin
+}
+
+func (s *Step) Run(context.Context, *check.S) error {
+	s.p.ran.Add(1)
+
+	if s.Fail != "" {
+		return errors.New("%s", s.Fail)
+	}
+
+	return nil
+}
+
+func (s *Step) Stop(context.Context) {}
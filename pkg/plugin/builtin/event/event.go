@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"strconv"
+	"sync"
 	"time"
 
 	"hookt.dev/cmd/pkg/check"
@@ -20,6 +21,8 @@ type Plugin struct {
 	steps []step
 	mux   chan proto.Message
 	stop  chan int
+	done  chan struct{}
+	once  sync.Once
 }
 
 type step struct {
@@ -31,6 +34,7 @@ func New(opts ...func(*Plugin)) *Plugin {
 	p := &Plugin{
 		mux:  make(chan proto.Message),
 		stop: make(chan int, 1),
+		done: make(chan struct{}),
 	}
 	for _, opt := range opts {
 		opt(p)
@@ -47,16 +51,64 @@ func (p *Plugin) Name() string {
 	return "event"
 }
 
+func (p *Plugin) Describe() (config, step any) {
+	return wire.Config{}, wire.Step{}
+}
+
+func (p *Plugin) Description() string {
+	return "Matches messages published by other plugins against jq patterns"
+}
+
 func (p *Plugin) Plugin(_ context.Context, q *proto.P) any {
-	return p.WithProto(q)
+	return New().WithProto(q)
 }
 
-func (p *Plugin) Init(ctx context.Context, job *proto.Job) error {
+func (p *Plugin) Validate(_ context.Context, job *proto.Job) error {
+	var err error
+
 	switch p.Config.Mode {
 	case "", "async", "sync":
 		// ok
 	default:
-		return errors.New("invalid mode %q", p.Config.Mode)
+		err = errors.Join(err, errors.New("invalid mode %q", p.Config.Mode))
+	}
+
+	for _, d := range []string{p.Config.Timeout, p.Config.InactiveTimeout} {
+		if d == "" {
+			continue
+		}
+		if _, e := time.ParseDuration(d); e != nil {
+			err = errors.Join(err, errors.New("invalid duration %q: %w", d, e))
+		}
+	}
+
+source:
+	for _, source := range p.Config.Sources {
+		for _, plugin := range job.Plugins {
+			if source != plugin.ID {
+				continue
+			}
+
+			if _, ok := plugin.With.(proto.Subscriber); !ok {
+				err = errors.Join(err, errors.New("plugin %q does not implement proto.Subscriber", plugin.Uses))
+			}
+
+			continue source
+		}
+
+		err = errors.Join(err, errors.New("source %q not found in job plugins", source))
+	}
+
+	if e := patterns(p.p, &p.Config.Pre); e != nil {
+		err = errors.Join(err, errors.New("invalid pre: %w", e))
+	}
+
+	return err
+}
+
+func (p *Plugin) Init(ctx context.Context, job *proto.Job) error {
+	if err := p.Validate(ctx, job); err != nil {
+		return err
 	}
 wire:
 	for _, source := range p.Config.Sources {
@@ -70,11 +122,7 @@ wire:
 				return errors.New("plugin %q does not implement proto.Subscriber", plugin.Uses)
 			}
 
-			go func() {
-				for msg := range sub.Subscribe(ctx) {
-					p.mux <- msg
-				}
-			}()
+			go p.forward(sub.Subscribe(ctx))
 
 			continue wire
 		}
@@ -91,11 +139,39 @@ wire:
 	return nil
 }
 
+// forward passes the messages of a source to the scheduler
+// until the source is closed or the plugin is closed.
+func (p *Plugin) forward(c <-chan proto.Message) {
+	for {
+		select {
+		case msg, ok := <-c:
+			if !ok {
+				return
+			}
+			select {
+			case p.mux <- msg:
+			case <-p.done:
+				return
+			}
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// Close stops the scheduler and the forwarding of messages.
+func (p *Plugin) Close(context.Context) error {
+	p.once.Do(func() { close(p.done) })
+	return nil
+}
+
 func (p *Plugin) schedule(ctx context.Context) {
 	tr := trace.ContextSchedule(ctx)
 
 	for {
 		select {
+		case <-p.done:
+			return
 		case i := <-p.stop:
 			s := &p.steps[i]
 			close(s.done)
@@ -122,6 +198,8 @@ func (p *Plugin) schedule(ctx context.Context) {
 						case <-step.done:
 							tr.Done(ctx, msg, i)
 							continue
+						case <-p.done:
+							return
 						case step.c <- wg:
 							tr.Mux(ctx, msg, i)
 
@@ -141,6 +219,8 @@ func (p *Plugin) schedule(ctx context.Context) {
 						case <-step.done:
 							tr.Done(ctx, msg, i)
 							continue
+						case <-p.done:
+							return
 						case step.c <- msg:
 							tr.Mux(ctx, msg, i)
 						}
@@ -168,16 +248,31 @@ func (p *Plugin) Step(ctx context.Context) any {
 type Step struct {
 	wire.Step
 
-	i  int
-	p  *Plugin
-	it time.Duration
+	i   int
+	p   *Plugin
+	it  time.Duration
+	obj any
+}
+
+func (s *Step) Validate(context.Context, *proto.Job) error {
+	return patterns(s.p.p, &s.Step)
+}
+
+// patterns compiles the patterns of the step.
+func patterns(p *proto.P, s *wire.Step) error {
+	return errors.Join(
+		p.CheckPatterns(s.Match),
+		p.CheckPatterns(s.Pass),
+		p.CheckPatterns(s.Fail),
+		p.CheckExports(s.Export),
+	)
 }
 
 func group(ctx context.Context, name string) context.Context {
 	return trace.With(ctx, "pattern-group", name)
 }
 
-func (s *Step) Run(ctx context.Context, c *check.S) error {
+func (s *Step) Run(ctx context.Context, _ *check.S) error {
 	slog.Debug("event: run",
 		"match", s.Match,
 		"pass", s.Pass,
@@ -205,9 +300,11 @@ func (s *Step) Run(ctx context.Context, c *check.S) error {
 	for {
 		select {
 		case <-inactive.C:
-			c.Fail()
 			tr.MatchTimeout(ctx)
-			return errors.New("step has timed out after %v", s.it)
+			return errors.New("step has %w after %v", check.ErrTimeout, s.it)
+		case <-ctx.Done():
+			tr.MatchTimeout(ctx)
+			return errors.New("step has been cancelled: %w", ctx.Err())
 		case msg := <-s.step().c:
 			if !inactive.Stop() {
 				<-inactive.C
@@ -235,19 +332,27 @@ func (s *Step) Run(ctx context.Context, c *check.S) error {
 				wg.Done(pass)
 			}
 			if pass {
-				return nil
+				s.obj = obj
+				return s.p.p.Export(ctxt, s.Export, obj)
 			}
 		}
 	}
+}
 
-	return nil
+// Outputs returns the matched message.
+func (s *Step) Outputs() any {
+	return s.obj
 }
 
 func (s *Step) Stop(ctx context.Context) {
 	tr := trace.ContextSchedule(ctx)
 
 	tr.BeforeStop(ctx, s.i)
-	s.p.stop <- s.i
+	select {
+	case s.p.stop <- s.i:
+	case <-s.p.done:
+		return
+	}
 	s.drain()
 	tr.Drain(ctx, s.i)
 }
@@ -265,6 +370,8 @@ func (s *Step) drain() {
 			}
 		case <-s.step().done:
 			return
+		case <-s.p.done:
+			return
 		}
 	}
 }
@@ -9,7 +9,7 @@ import (
 )
 
 type Config struct {
-	Sources         []string `json:"sources"`
+	Sources         []string `json:"sources" jsonschema:"required"`
 	Mode            string   `json:"mode,omitempty"`
 	Timeout         string   `json:"timeout,omitempty"`
 	InactiveTimeout string   `json:"inactive_timeout,omitempty"`
@@ -39,4 +39,8 @@ type Step struct {
 	Match wire.Object `json:"match"`
 	Pass  wire.Object `json:"pass,omitempty"`
 	Fail  wire.Object `json:"fail,omitempty"`
+
+	// Export maps jq queries to the variables their first result
+	// against the matched message is stored into.
+	Export map[string]string `json:"export,omitempty"`
 }
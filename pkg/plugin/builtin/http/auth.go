@@ -0,0 +1,286 @@
+package http
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hookt.dev/cmd/pkg/plugin/builtin/http/wire"
+)
+
+// expiryDelta is how long before their expiry tokens are refreshed.
+const expiryDelta = 10 * time.Second
+
+type auth struct {
+	header string // static Authorization header
+	token  *tokenSource
+	sign   *signer
+}
+
+func validateAuth(a *wire.Auth) error {
+	var n int
+	if a.Basic != nil {
+		n++
+	}
+	if a.Bearer != "" {
+		n++
+	}
+	if a.OAuth2 != nil {
+		n++
+	}
+	if n > 1 {
+		return errors.New("auth: only one of basic, bearer and oauth2 may be set")
+	}
+
+	if h := a.HMAC; h != nil {
+		if _, err := algorithm(h.Algorithm); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+		if _, err := encoding(h.Encoding); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// auth builds the authentication of the plugin, evaluating
+// the credentials of the config.
+func (p *Plugin) auth(ctx context.Context, a *wire.Auth) (*auth, error) {
+	if err := validateAuth(a); err != nil {
+		return nil, err
+	}
+
+	var (
+		res auth
+		err error
+	)
+
+	eval := func(s string) string {
+		if err != nil {
+			return ""
+		}
+		var v string
+		v, err = p.evaluate(ctx, s)
+		return v
+	}
+
+	switch {
+	case a.Basic != nil:
+		cred := eval(a.Basic.Username) + ":" + eval(a.Basic.Password)
+		res.header = "Basic " + base64.StdEncoding.EncodeToString([]byte(cred))
+	case a.Bearer != "":
+		res.header = "Bearer " + eval(a.Bearer)
+	case a.OAuth2 != nil:
+		res.token = &tokenSource{
+			c:      p.c,
+			url:    eval(a.OAuth2.TokenURL),
+			id:     eval(a.OAuth2.ClientID),
+			secret: eval(a.OAuth2.ClientSecret),
+			scopes: a.OAuth2.Scopes,
+			params: a.OAuth2.Params,
+		}
+	}
+
+	if h := a.HMAC; h != nil {
+		res.sign = &signer{
+			secret:    []byte(eval(h.Secret)),
+			header:    h.Header,
+			prefix:    h.Prefix,
+			timestamp: h.TimestampHeader,
+		}
+
+		if res.sign.header == "" {
+			res.sign.header = "X-Signature"
+		}
+
+		res.sign.hash, _ = algorithm(h.Algorithm)
+		res.sign.encode, _ = encoding(h.Encoding)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate auth: %w", err)
+	}
+
+	return &res, nil
+}
+
+// apply authenticates and signs the request with the given body.
+func (a *auth) apply(ctx context.Context, req *http.Request, body []byte) error {
+	switch {
+	case a.header != "":
+		req.Header.Set("Authorization", a.header)
+	case a.token != nil:
+		token, err := a.token.get(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	if a.sign != nil {
+		a.sign.apply(req, body)
+	}
+
+	return nil
+}
+
+// signs reports whether requests are signed, which requires their body.
+func (a *auth) signs() bool {
+	return a != nil && a.sign != nil
+}
+
+// invalidate drops the cached token after it was rejected.
+func (a *auth) invalidate() {
+	if a != nil && a.token != nil {
+		a.token.invalidate()
+	}
+}
+
+// tokenSource gets tokens with the OAuth2 client credentials grant,
+// caching them until they expire.
+type tokenSource struct {
+	c      *http.Client
+	url    string
+	id     string
+	secret string
+	scopes []string
+	params map[string]string
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func (ts *tokenSource) get(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token != "" && (ts.expiry.IsZero() || time.Now().Add(expiryDelta).Before(ts.expiry)) {
+		return ts.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(ts.scopes) != 0 {
+		form.Set("scope", strings.Join(ts.scopes, " "))
+	}
+	for k, v := range ts.params {
+		form.Set(k, v)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to get token: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(ts.id), url.QueryEscape(ts.secret))
+
+	resp, err := ts.c.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	p, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to get token: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get token: token endpoint returned %d: %s", resp.StatusCode, p)
+	}
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+
+	if err := json.Unmarshal(p, &tok); err != nil {
+		return "", fmt.Errorf("failed to decode token: %w", err)
+	}
+
+	if tok.AccessToken == "" {
+		return "", errors.New("failed to get token: no access_token in response")
+	}
+
+	ts.token = tok.AccessToken
+	ts.expiry = time.Time{}
+
+	if tok.ExpiresIn > 0 {
+		ts.expiry = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	}
+
+	return ts.token, nil
+}
+
+func (ts *tokenSource) invalidate() {
+	ts.mu.Lock()
+	ts.token = ""
+	ts.mu.Unlock()
+}
+
+// signer sets the HMAC signature of requests.
+type signer struct {
+	secret    []byte
+	hash      func() hash.Hash
+	encode    func([]byte) string
+	header    string
+	prefix    string
+	timestamp string
+}
+
+func (s *signer) apply(req *http.Request, body []byte) {
+	mac := hmac.New(s.hash, s.secret)
+
+	if s.timestamp != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(s.timestamp, ts)
+
+		mac.Write([]byte(ts + "."))
+	}
+
+	mac.Write(body)
+
+	req.Header.Set(s.header, s.prefix+s.encode(mac.Sum(nil)))
+}
+
+func algorithm(name string) (func() hash.Hash, error) {
+	switch name {
+	case "", "sha256":
+		return sha256.New, nil
+	case "sha1":
+		return sha1.New, nil
+	case "sha512":
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported hmac algorithm %q", name)
+	}
+}
+
+func encoding(name string) (func([]byte) string, error) {
+	switch name {
+	case "", "hex":
+		return hex.EncodeToString, nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString, nil
+	default:
+		return nil, fmt.Errorf("unsupported hmac encoding %q", name)
+	}
+}
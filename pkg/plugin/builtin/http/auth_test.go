@@ -0,0 +1,197 @@
+package http_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"hookt.dev/cmd/pkg/check"
+	"hookt.dev/cmd/pkg/hookt"
+	"hookt.dev/cmd/pkg/proto"
+)
+
+func echoAuth() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"auth": r.Header.Get("Authorization"),
+		})
+	}))
+}
+
+func TestAuth(t *testing.T) {
+	srv := echoAuth()
+	defer srv.Close()
+
+	cases := map[string]struct {
+		config string
+		want   string
+	}{
+		"basic": {`
+          auth:
+            basic:
+              username: user
+              password: pass`,
+			"Basic dXNlcjpwYXNz",
+		},
+		"bearer": {`
+          auth:
+            bearer: ${{ var "url" | len }}`,
+			"Bearer " + strconv.Itoa(len(srv.URL)),
+		},
+	}
+
+	for name, cas := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := runConfig(t, cas.config, srv.URL, `
+            body:
+              .auth: `+cas.want)
+
+			if got := status(t, s); got != check.StatusPass {
+				t.Errorf("got status %q, want %q (%s)", got, check.StatusPass, s.Records[0].Error)
+			}
+		})
+	}
+}
+
+func TestOAuth2(t *testing.T) {
+	srv := echoAuth()
+	defer srv.Close()
+
+	for name, cas := range map[string]struct {
+		expiresIn int
+		fetches   int64
+	}{
+		"cached":  {3600, 1},
+		"expired": {5, 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var n atomic.Int64
+
+			token := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id, secret, _ := r.BasicAuth()
+				if r.PostFormValue("grant_type") != "client_credentials" || id != "hkt" || secret != "s3cret" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				fmt.Fprintf(w, `{"access_token": "%s-%d", "expires_in": %d}`, r.PostFormValue("scope"), n.Add(1), cas.expiresIn)
+			}))
+			defer token.Close()
+
+			workflow := `
+jobs:
+  - id: oauth2
+    plugins:
+      - uses: http
+        with:
+          auth:
+            oauth2:
+              token_url: ${{ var "token-url" }}
+              client_id: hkt
+              client_secret: s3cret
+              scopes: [read, write]
+    steps:
+      - uses: http
+        id: first
+        with:
+          request:
+            url: ${{ var "url" }}
+          response:
+            body:
+              .auth: Bearer read write-1
+      - uses: http
+        wait_for: first
+        with:
+          request:
+            url: ${{ var "url" }}
+          response:
+            body:
+              .auth | startswith("Bearer read write-"): true
+`
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ngn := hookt.New(hookt.WithProtoOptions(proto.WithVars(map[string]any{
+				"url":       srv.URL,
+				"token-url": token.URL,
+			})))
+
+			s, err := ngn.Run(ctx, []byte(workflow))
+			if err != nil {
+				t.Fatalf("Run()=%v", err)
+			}
+
+			if res := s.Results(); len(res) != 0 {
+				t.Fatalf("Results()=%+v", res)
+			}
+
+			if got := n.Load(); got != cas.fetches {
+				t.Errorf("got %d token requests, want %d", got, cas.fetches)
+			}
+		})
+	}
+}
+
+func TestHMAC(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write([]byte(r.Header.Get("X-Timestamp") + "."))
+		mac.Write(body)
+
+		json.NewEncoder(w).Encode(map[string]bool{
+			"valid": r.Header.Get("X-Hub-Signature") == "sha256="+hex.EncodeToString(mac.Sum(nil)),
+		})
+	}))
+	defer srv.Close()
+
+	workflow := `
+jobs:
+  - id: hmac
+    plugins:
+      - uses: http
+        with:
+          auth:
+            hmac:
+              secret: s3cret
+              header: X-Hub-Signature
+              prefix: sha256=
+              timestamp_header: X-Timestamp
+    steps:
+      - uses: http
+        with:
+          request:
+            method: POST
+            url: ${{ var "url" }}
+            body: '{"event": "ping"}'
+          response:
+            body:
+              .valid: true
+`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ngn := hookt.New(hookt.WithProtoOptions(proto.WithVars(map[string]any{"url": srv.URL})))
+
+	s, err := ngn.Run(ctx, []byte(workflow))
+	if err != nil {
+		t.Fatalf("Run()=%v", err)
+	}
+
+	if res := s.Results(); len(res) != 0 {
+		t.Fatalf("Results()=%+v", res)
+	}
+}
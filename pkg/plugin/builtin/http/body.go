@@ -0,0 +1,156 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// decode decodes the body by its content type: JSON, form-encoded
+// and XML bodies become objects, others are kept as text. Bodies
+// without a known content type are decoded as JSON when valid.
+func decode(contentType string, p []byte) (body any, mediaType string, err error) {
+	mediaType, _, _ = mime.ParseMediaType(contentType)
+
+	if len(bytes.TrimSpace(p)) == 0 {
+		return nil, mediaType, nil
+	}
+
+	switch {
+	case isJSON(mediaType):
+		if err := json.Unmarshal(p, &body); err != nil {
+			return nil, mediaType, fmt.Errorf("failed to decode %s body: %w", mediaType, err)
+		}
+		return body, mediaType, nil
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(p))
+		if err != nil {
+			return nil, mediaType, fmt.Errorf("failed to decode %s body: %w", mediaType, err)
+		}
+		return form(values), mediaType, nil
+	case isXML(mediaType):
+		body, err := decodeXML(p)
+		if err != nil {
+			return nil, mediaType, fmt.Errorf("failed to decode %s body: %w", mediaType, err)
+		}
+		return body, mediaType, nil
+	case mediaType == "" || mediaType == "text/plain":
+		if json.Valid(p) {
+			if err := json.Unmarshal(p, &body); err == nil {
+				return body, mediaType, nil
+			}
+		}
+	}
+
+	return string(p), mediaType, nil
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func isXML(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// form returns the values, keeping a single value as a string
+// and multiple ones as an array.
+func form(values url.Values) map[string]any {
+	m := make(map[string]any, len(values))
+
+	for k, v := range values {
+		if len(v) == 1 {
+			m[k] = v[0]
+			continue
+		}
+
+		a := make([]any, len(v))
+		for i := range v {
+			a[i] = v[i]
+		}
+		m[k] = a
+	}
+
+	return m
+}
+
+// decodeXML decodes the document into an object keyed by the name
+// of its root element. Elements with only text become strings,
+// others objects with their attributes prefixed with @, their text
+// under #text and their child elements by name, repeated ones
+// becoming arrays.
+func decodeXML(p []byte) (any, error) {
+	dec := xml.NewDecoder(bytes.NewReader(p))
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, errors.New("no root element")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if start, ok := tok.(xml.StartElement); ok {
+			v, err := element(dec, start)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{start.Name.Local: v}, nil
+		}
+	}
+}
+
+func element(dec *xml.Decoder, start xml.StartElement) (any, error) {
+	var (
+		m    = make(map[string]any)
+		text strings.Builder
+	)
+
+	for _, attr := range start.Attr {
+		m["@"+attr.Name.Local] = attr.Value
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			v, err := element(dec, tok)
+			if err != nil {
+				return nil, err
+			}
+
+			switch prev := m[tok.Name.Local].(type) {
+			case nil:
+				m[tok.Name.Local] = v
+			case []any:
+				m[tok.Name.Local] = append(prev, v)
+			default:
+				m[tok.Name.Local] = []any{prev, v}
+			}
+		case xml.CharData:
+			text.Write(tok)
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+
+			if len(m) == 0 {
+				return s, nil
+			}
+			if s != "" {
+				m["#text"] = s
+			}
+
+			return m, nil
+		}
+	}
+}
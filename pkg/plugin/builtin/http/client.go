@@ -0,0 +1,130 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"hookt.dev/cmd/pkg/plugin/builtin/http/wire"
+)
+
+// DefaultRedirects is the number of redirects followed by default.
+const DefaultRedirects = 10
+
+// client builds the client shared by the steps of the plugin.
+func (p *Plugin) client(ctx context.Context) (*http.Client, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+
+	if c := p.Config.TLS; c != nil {
+		cfg, err := p.tls(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+
+		tr.TLSClientConfig = cfg
+	}
+
+	if p.Config.Proxy != "" {
+		s, err := p.evaluate(ctx, p.Config.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate proxy: %w", err)
+		}
+
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %w", s, err)
+		}
+
+		tr.Proxy = http.ProxyURL(u)
+	}
+
+	if h2 := p.Config.HTTP2; h2 != nil && !*h2 {
+		tr.ForceAttemptHTTP2 = false
+		tr.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+
+	max := DefaultRedirects
+	if n := p.Config.Redirects; n != nil {
+		max = *n
+	}
+
+	return &http.Client{
+		Transport: tr,
+		Timeout:   p.Config.GetTimeout(),
+		CheckRedirect: func(_ *http.Request, via []*http.Request) error {
+			if len(via) > max {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}, nil
+}
+
+func (p *Plugin) tls(ctx context.Context, c *wire.TLS) (*tls.Config, error) {
+	var (
+		cfg = &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+		err error
+	)
+
+	if cfg.ServerName, err = p.evaluate(ctx, c.ServerName); err != nil {
+		return nil, fmt.Errorf("failed to evaluate tls server name: %w", err)
+	}
+
+	if c.CA != "" {
+		path, err := p.evaluate(ctx, c.CA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate tls ca: %w", err)
+		}
+
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls ca: %w", err)
+		}
+
+		if cfg.RootCAs, err = x509.SystemCertPool(); err != nil {
+			cfg.RootCAs = x509.NewCertPool()
+		}
+
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in tls ca %q", path)
+		}
+	}
+
+	if c.Cert != "" {
+		cert, err := p.evaluate(ctx, c.Cert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate tls cert: %w", err)
+		}
+
+		key, err := p.evaluate(ctx, c.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate tls key: %w", err)
+		}
+
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tls cert: %w", err)
+		}
+
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+
+	return cfg, nil
+}
+
+func (p *Plugin) evaluate(ctx context.Context, s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	q, err := p.p.EvaluateContext(ctx, s, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(q), nil
+}
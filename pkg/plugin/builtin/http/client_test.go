@@ -0,0 +1,294 @@
+package http_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"hookt.dev/cmd/pkg/check"
+	"hookt.dev/cmd/pkg/hookt"
+	"hookt.dev/cmd/pkg/proto"
+)
+
+func runConfig(t *testing.T, config, url, response string) *check.S {
+	t.Helper()
+
+	workflow := `
+jobs:
+  - id: http
+    plugins:
+      - uses: http
+        with:
+          timeout: 5s
+` + config + `
+    steps:
+      - uses: http
+        with:
+          request:
+            url: ${{ var "url" }}
+          response:
+` + response
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ngn := hookt.New(hookt.WithProtoOptions(proto.WithVars(map[string]any{"url": url})))
+
+	s, err := ngn.Run(ctx, []byte(workflow))
+	if s == nil {
+		t.Fatalf("Run()=%v", err)
+	}
+
+	return s
+}
+
+func status(t *testing.T, s *check.S) check.Status {
+	t.Helper()
+
+	if len(s.Records) != 1 {
+		t.Fatalf("Records=%+v", s.Records)
+	}
+
+	return s.Records[0].Status
+}
+
+// writePEM writes the blocks to a file of the temporary directory.
+func writePEM(t *testing.T, dir, name string, blocks ...*pem.Block) string {
+	t.Helper()
+
+	var p []byte
+	for _, b := range blocks {
+		p = append(p, pem.EncodeToMemory(b)...)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, p, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// clientCert returns a self-signed client certificate and its key.
+func clientCert(t *testing.T) (*x509.Certificate, *pem.Block, *pem.Block) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "hkt"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, &pem.Block{Type: "CERTIFICATE", Bytes: der}, &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}
+}
+
+func TestTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"ok": true}`)
+	}))
+	defer srv.Close()
+
+	var (
+		dir = t.TempDir()
+		ca  = writePEM(t, dir, "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	)
+
+	cases := map[string]struct {
+		config string
+		ok     bool
+	}{
+		"untrusted": {
+			"",
+			false,
+		},
+		"ca": {`
+          tls:
+            ca: ` + ca,
+			true,
+		},
+		"server name": {`
+          tls:
+            ca: ` + ca + `
+            server_name: example.com`,
+			true,
+		},
+		"wrong server name": {`
+          tls:
+            ca: ` + ca + `
+            server_name: example.org`,
+			false,
+		},
+		"insecure": {`
+          tls:
+            insecure_skip_verify: true`,
+			true,
+		},
+	}
+
+	for name, cas := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := runConfig(t, cas.config, srv.URL, `
+            body:
+              .ok: true`)
+
+			if got := status(t, s); (got == check.StatusPass) != cas.ok {
+				t.Errorf("got status %q, want ok=%v (%s)", got, cas.ok, s.Records[0].Error)
+			}
+		})
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	cert, certPEM, keyPEM := clientCert(t)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"cn": "`+r.TLS.PeerCertificates[0].Subject.CommonName+`"}`)
+	}))
+	srv.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	var (
+		dir  = t.TempDir()
+		ca   = writePEM(t, dir, "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+		crt  = writePEM(t, dir, "client.pem", certPEM)
+		key  = writePEM(t, dir, "client-key.pem", keyPEM)
+		resp = `
+            body:
+              .cn: hkt`
+	)
+
+	s := runConfig(t, `
+          tls:
+            ca: `+ca, srv.URL, resp)
+	if got := status(t, s); got == check.StatusPass {
+		t.Errorf("got status %q without client cert", got)
+	}
+
+	s = runConfig(t, `
+          tls:
+            ca: `+ca+`
+            cert: `+crt+`
+            key: `+key, srv.URL, resp)
+	if got := status(t, s); got != check.StatusPass {
+		t.Errorf("got status %q, want %q (%s)", got, check.StatusPass, s.Records[0].Error)
+	}
+}
+
+func TestHTTP2(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"proto": `+strconv.Itoa(r.ProtoMajor)+`}`)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	for http2, want := range map[string]string{"true": "2", "false": "1"} {
+		t.Run(http2, func(t *testing.T) {
+			s := runConfig(t, `
+          http2: `+http2+`
+          tls:
+            insecure_skip_verify: true`, srv.URL, `
+            body:
+              .proto: `+want)
+
+			if got := status(t, s); got != check.StatusPass {
+				t.Errorf("got status %q, want %q (%s)", got, check.StatusPass, s.Records[0].Error)
+			}
+		})
+	}
+}
+
+func TestProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"url": "`+r.URL.String()+`"}`)
+	}))
+	defer proxy.Close()
+
+	s := runConfig(t, `
+          proxy: `+proxy.URL, "http://example.invalid/path", `
+            body:
+              .url: http://example.invalid/path`)
+
+	if got := status(t, s); got != check.StatusPass {
+		t.Errorf("got status %q, want %q (%s)", got, check.StatusPass, s.Records[0].Error)
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/a", http.RedirectHandler("/b", http.StatusFound))
+	mux.Handle("/b", http.RedirectHandler("/c", http.StatusFound))
+	mux.HandleFunc("/c", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"ok": true}`)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cases := map[string]struct {
+		config string
+		status string
+	}{
+		"default": {"", "200"},
+		"none":    {"          redirects: 0", "302"},
+		"one":     {"          redirects: 1", "302"},
+		"two":     {"          redirects: 2", "200"},
+	}
+
+	for name, cas := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := runConfig(t, cas.config, srv.URL+"/a", `
+            status: `+cas.status)
+
+			if got := status(t, s); got != check.StatusPass {
+				t.Errorf("got status %q, want %q (%s)", got, check.StatusPass, s.Records[0].Error)
+			}
+		})
+	}
+}
@@ -4,22 +4,28 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
 
 	"hookt.dev/cmd/pkg/check"
 	"hookt.dev/cmd/pkg/plugin/builtin/http/wire"
 	"hookt.dev/cmd/pkg/proto"
 	protowire "hookt.dev/cmd/pkg/proto/wire"
 	"hookt.dev/cmd/pkg/trace"
+
+	"github.com/lmittmann/tint"
 )
 
 type Plugin struct {
 	wire.Config
 
 	h http.Header
+	c *http.Client
+	a *auth
 	p *proto.P
 }
 
@@ -27,6 +33,14 @@ func (p *Plugin) Name() string {
 	return "http"
 }
 
+func (p *Plugin) Describe() (config, step any) {
+	return wire.Config{}, wire.Step{}
+}
+
+func (p *Plugin) Description() string {
+	return "Sends HTTP requests and checks their responses"
+}
+
 func New(opts ...func(*Plugin)) *Plugin {
 	p := &Plugin{}
 	for _, opt := range opts {
@@ -41,76 +55,183 @@ func (p *Plugin) WithProto(q *proto.P) *Plugin {
 }
 
 func (p *Plugin) Plugin(_ context.Context, q *proto.P) any {
-	return p.WithProto(q)
+	return New().WithProto(q)
 }
 
-func (p *Plugin) Init(_ context.Context, job *proto.Job) (err error) {
+func (p *Plugin) Validate(context.Context, *proto.Job) error {
+	var err error
+
+	if p.Config.Timeout != "" {
+		if _, e := time.ParseDuration(p.Config.Timeout); e != nil {
+			err = errors.Join(err, fmt.Errorf("invalid timeout %q: %w", p.Config.Timeout, e))
+		}
+	}
+
+	if c := p.Config.TLS; c != nil && (c.Cert == "") != (c.Key == "") {
+		err = errors.Join(err, errors.New("tls cert and key must be set together"))
+	}
+
+	if n := p.Config.Redirects; n != nil && *n < 0 {
+		err = errors.Join(err, fmt.Errorf("invalid redirects %d", *n))
+	}
+
+	if a := p.Config.Auth; a != nil {
+		err = errors.Join(err, validateAuth(a))
+	}
+
+	return err
+}
+
+func (p *Plugin) Init(ctx context.Context, job *proto.Job) (err error) {
 	slog.Debug("http: init",
 		"config", p.Config,
 	)
 
-	p.h, err = wire.Headers(p.Config.Headers, p.p)
+	p.h, err = wire.Headers(ctx, p.Config.Headers, p.p)
+	if err != nil {
+		return err
+	}
+
+	p.c, err = p.client(ctx)
 	if err != nil {
 		return err
 	}
 
+	if a := p.Config.Auth; a != nil {
+		if p.a, err = p.auth(ctx, a); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (p *Plugin) Step(context.Context) any {
-	return &Step{
-		p: p,
-		c: &http.Client{
-			Timeout: p.Config.GetTimeout(),
-		},
+// Close closes the idle connections of the client.
+func (p *Plugin) Close(context.Context) error {
+	if p.c != nil {
+		p.c.CloseIdleConnections()
 	}
+	return nil
+}
+
+func (p *Plugin) Step(context.Context) any {
+	return &Step{p: p}
 }
 
 type Step struct {
 	wire.Step `json:",inline"`
 
 	p *Plugin
-	c *http.Client
+	r *Response
+}
+
+func (s *Step) Validate(context.Context, *proto.Job) error {
+	_, err := makeRetry(s.Retry, len(s.Response.Until) != 0)
+
+	return errors.Join(
+		err,
+		validatePayload(&s.Request),
+		s.p.p.CheckPatterns(s.Response.Until),
+		s.p.p.CheckPatterns(s.Response.Match),
+		s.p.p.CheckPatterns(s.pass()),
+		s.p.p.CheckPatterns(s.Response.Fail),
+	)
 }
 
-func (s *Step) Run(ctx context.Context, _ *check.S) error {
-	req, err := s.req(ctx, &s.Request)
+func (s *Step) Run(ctx context.Context, cs *check.S) error {
+	rt, err := makeRetry(s.Retry, len(s.Response.Until) != 0)
 	if err != nil {
 		return err
 	}
 
-	ctx = group(ctx, "pass")
-
-	pass, err := s.p.p.Patterns(ctx, s.Step.Response.Pass)
+	sns, err := s.sensor(ctx)
 	if err != nil {
 		return err
 	}
 
-	resp, err := s.c.Do(req)
+	return s.retry(ctx, cs, rt, sns)
+}
+
+// pass returns the pass patterns along with the
+// status, headers and body shorthands.
+func (s *Step) pass() protowire.Object {
+	var (
+		resp = &s.Response
+		obj  = make(protowire.Object, len(resp.Pass)+len(resp.Headers)+len(resp.Body)+1)
+	)
+
+	for k, v := range resp.Pass {
+		obj[k] = v
+	}
+
+	if len(resp.Status) != 0 {
+		obj[".status"] = resp.Status
+	}
+
+	for k, v := range resp.Headers {
+		obj[".header["+strconv.Quote(http.CanonicalHeaderKey(k))+"]"] = v
+	}
+
+	for k, v := range resp.Body {
+		obj[".body | "+k] = v
+	}
+
+	return obj
+}
+
+type sensor struct {
+	until, match, pass, fail proto.Patterns
+}
+
+func (s *Step) sensor(ctx context.Context) (*sensor, error) {
+	var (
+		sns sensor
+		err error
+	)
+
+	sns.until, err = s.p.p.Patterns(group(ctx, "until"), s.Response.Until)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to parse until pattern: %w", err)
 	}
-	defer resp.Body.Close()
 
-	p, err := io.ReadAll(resp.Body)
+	sns.match, err = s.p.p.Patterns(group(ctx, "match"), s.Response.Match)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to parse match pattern: %w", err)
 	}
 
-	r := &Response{
-		Status:  resp.StatusCode,
-		Headers: make(map[string]string),
+	sns.fail, err = s.p.p.Patterns(group(ctx, "fail"), s.Response.Fail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fail pattern: %w", err)
+	}
+
+	sns.pass, err = s.p.p.Patterns(group(ctx, "pass"), s.pass())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pass pattern: %w", err)
 	}
 
-	if err := json.Unmarshal(p, &r.Body); err != nil {
+	return &sns, nil
+}
+
+// do checks the response object against the match, fail
+// and pass patterns, in that order.
+func (sns *sensor) do(ctx context.Context, obj any) error {
+	ok, err := sns.match.Match(group(ctx, "match"), obj)
+	if err != nil {
 		return err
 	}
+	if !ok {
+		return errors.New("response did not match match pattern")
+	}
 
-	for k := range resp.Header {
-		r.Headers[k] = resp.Header.Get(k)
+	fail, err := sns.fail.Match(group(ctx, "fail"), obj)
+	if err != nil {
+		return err
+	}
+	if fail && len(sns.fail) != 0 {
+		return errors.New("failure pattern matched")
 	}
 
-	ok, err := pass.Match(ctx, r.Object())
+	ok, err = sns.pass.Match(group(ctx, "pass"), obj)
 	if err != nil {
 		return err
 	}
@@ -121,58 +242,132 @@ func (s *Step) Run(ctx context.Context, _ *check.S) error {
 	return nil
 }
 
+// Outputs returns the response, with its status, header and body.
+func (s *Step) Outputs() any {
+	if s.r == nil {
+		return nil
+	}
+	return s.r.Object()
+}
+
 func group(ctx context.Context, name string) context.Context {
 	return trace.With(ctx, "pattern-group", name)
 }
 
 type Response struct {
-	Status  int               `json:"status,omitempty"`
-	Headers map[string]string `json:"header,omitempty"`
-	Body    any               `json:"body,omitempty"`
+	Status      int               `json:"status,omitempty"`
+	Headers     map[string]string `json:"header,omitempty"`
+	Body        any               `json:"body,omitempty"`
+	Raw         string            `json:"raw,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+}
+
+func makeResponse(resp *http.Response) (*Response, error) {
+	p, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Response{
+		Status:  resp.StatusCode,
+		Headers: make(map[string]string),
+		Raw:     string(p),
+	}
+
+	for k := range resp.Header {
+		r.Headers[k] = resp.Header.Get(k)
+	}
+
+	// A body that does not decode as its content type says, e.g. an
+	// HTML error page of a proxy, is kept as text so that the status
+	// can still be asserted on or retried.
+	r.Body, r.ContentType, err = decode(resp.Header.Get("Content-Type"), p)
+	if err != nil {
+		slog.Debug("http: keeping body as text",
+			"status", r.Status,
+			"content_type", r.ContentType,
+			tint.Err(err),
+		)
+
+		r.Body = r.Raw
+	}
+
+	return r, nil
 }
 
 func (r *Response) Object() map[string]any {
+	header := make(map[string]any, len(r.Headers))
+	for k, v := range r.Headers {
+		header[k] = v
+	}
 	return map[string]any{
-		"status": r.Status,
-		"header": r.Headers,
-		"body":   r.Body,
+		"status":       r.Status,
+		"header":       header,
+		"body":         r.Body,
+		"raw":          r.Raw,
+		"content_type": r.ContentType,
+	}
+}
+
+// template evaluates the templates of the fields of v into out.
+func (s *Step) template(ctx context.Context, v, out any) error {
+	var obj protowire.Object
+
+	p, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(p, &obj); err != nil {
+		return err
 	}
+
+	return s.p.p.Template(ctx, obj, out)
 }
 
 func (s *Step) req(ctx context.Context, raw *wire.Request) (*http.Request, error) {
 	var (
 		res     wire.Request
-		obj     protowire.Object
 		headers = raw.Headers
+		tmpl    = *raw
 	)
 
-	raw.Headers = nil
+	tmpl.Headers = nil
 
-	p, err := json.Marshal(raw)
-	if err != nil {
+	if err := s.template(ctx, &tmpl, &res); err != nil {
 		return nil, err
 	}
 
-	if err := json.Unmarshal(p, &obj); err != nil {
+	pl, err := s.payload(ctx, &res)
+	if err != nil {
 		return nil, err
 	}
 
-	if err := s.p.p.Template(ctx, obj, &res); err != nil {
+	h, err := wire.Headers(ctx, headers, s.p.p)
+	if err != nil {
+		pl.close()
 		return nil, err
 	}
 
-	var body io.Reader
-	if res.Body != "" {
-		body = strings.NewReader(res.Body)
-	}
+	var (
+		r    io.Reader
+		body []byte
+	)
 
-	h, err := wire.Headers(headers, s.p.p)
-	if err != nil {
-		return nil, err
+	if pl != nil {
+		if s.p.a.signs() {
+			if body, err = pl.bytes(); err != nil {
+				pl.close()
+				return nil, err
+			}
+		}
+
+		r = pl.r
 	}
 
-	req, err := http.NewRequestWithContext(ctx, res.Method, res.URL, body)
+	req, err := http.NewRequestWithContext(ctx, res.Method, res.URL, r)
 	if err != nil {
+		pl.close()
 		return nil, err
 	}
 
@@ -180,6 +375,21 @@ func (s *Step) req(ctx context.Context, raw *wire.Request) (*http.Request, error
 		req.Header.Set(k, s.p.h.Get(k))
 	}
 
+	if pl != nil {
+		req.ContentLength = pl.size
+
+		if pl.contentType != "" {
+			req.Header.Set("Content-Type", pl.contentType)
+		}
+	}
+
+	if s.p.a != nil {
+		if err := s.p.a.apply(ctx, req, body); err != nil {
+			pl.close()
+			return nil, err
+		}
+	}
+
 	for k := range h {
 		req.Header.Set(k, h.Get(k))
 	}
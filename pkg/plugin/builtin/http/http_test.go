@@ -0,0 +1,282 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"hookt.dev/cmd/pkg/check"
+	"hookt.dev/cmd/pkg/hookt"
+	"hookt.dev/cmd/pkg/proto"
+)
+
+func server(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, "pong")
+	})
+	mux.HandleFunc("/xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		io.WriteString(w, `<order id="1"><item>a</item><item>b</item><state>open</state></order>`)
+	})
+	mux.HandleFunc("/form", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+		io.WriteString(w, "token=abc&scope=read&scope=write")
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.Header().Set("X-Request-Id", "42")
+		w.WriteHeader(http.StatusConflict)
+		io.WriteString(w, `{"title": "conflict"}`)
+	})
+	mux.HandleFunc("/unavailable", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, "<html><body>Service Unavailable</body></html>")
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func run(t *testing.T, url, with string) *check.S {
+	t.Helper()
+
+	workflow := `
+jobs:
+  - id: http
+    plugins:
+      - uses: http
+        with:
+          timeout: 5s
+    steps:
+      - uses: http
+        with:
+          request:
+            url: ${{ var "url" }}
+` + with
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ngn := hookt.New(hookt.WithProtoOptions(proto.WithVars(map[string]any{"url": url})))
+
+	s, err := ngn.Run(ctx, []byte(workflow))
+	if s == nil {
+		t.Fatalf("Run()=%v", err)
+	}
+
+	return s
+}
+
+func TestResponse(t *testing.T) {
+	srv := server(t)
+
+	cases := map[string]struct {
+		path     string
+		response string
+	}{
+		"text": {
+			"/text", `
+            status: 200
+            body:
+              .: pong
+            pass:
+              .raw: pong
+              .content_type: text/plain`,
+		},
+		"xml": {
+			"/xml", `
+            body:
+              .order["@id"]: "1"
+              .order.item[1]: b
+              .order.state: open`,
+		},
+		"form": {
+			"/form", `
+            body:
+              .token: abc
+              .scope: [read, write]`,
+		},
+		"json": {
+			"/json", `
+            status: 409
+            headers:
+              x-request-id: "42"
+            body:
+              .title: conflict`,
+		},
+		"invalid json": {
+			"/unavailable", `
+            status: 503
+            body:
+              .: <html><body>Service Unavailable</body></html>`,
+		},
+		"empty": {
+			"/empty", `
+            status: 204`,
+		},
+		"groups": {
+			"/json", `
+            match:
+              .content_type: application/problem+json
+            fail:
+              .status: 500
+            pass:
+              .body.title: conflict`,
+		},
+	}
+
+	for name, cas := range cases {
+		t.Run(name, func(t *testing.T) {
+			if res := run(t, srv.URL+cas.path, "          response:"+cas.response).Results(); len(res) != 0 {
+				t.Fatalf("Results()=%+v", res)
+			}
+		})
+	}
+}
+
+func TestResponseFailure(t *testing.T) {
+	srv := server(t)
+
+	cases := map[string]struct {
+		path     string
+		response string
+		typ      string
+	}{
+		"status": {
+			"/json", `
+            status: 200`,
+			"pass",
+		},
+		"body": {
+			"/xml", `
+            body:
+              .order.state: closed`,
+			"pass",
+		},
+		"match": {
+			"/text", `
+            match:
+              .content_type: application/json`,
+			"match",
+		},
+		"fail": {
+			"/json", `
+            fail:
+              .status: 409`,
+			"fail",
+		},
+	}
+
+	for name, cas := range cases {
+		t.Run(name, func(t *testing.T) {
+			res := run(t, srv.URL+cas.path, "          response:"+cas.response).Results()
+			if len(res) != 1 || res[0].Type != cas.typ {
+				t.Fatalf("Results()=%+v, want %s failure", res, cas.typ)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var n atomic.Int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch n.Add(1) {
+		case 1, 2:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, "<html>Service Unavailable</html>")
+		case 3:
+			io.WriteString(w, `{"state": "pending"}`)
+		default:
+			io.WriteString(w, `{"state": "ready"}`)
+		}
+	}))
+	defer srv.Close()
+
+	cases := map[string]struct {
+		with     string
+		status   check.Status
+		attempts int
+	}{
+		"until": {`
+          retry:
+            attempts: 5
+            interval: 10ms
+            backoff: 2
+            on_status: [503]
+          response:
+            until:
+              .body.state: ready
+            status: 200`,
+			check.StatusPass, 4,
+		},
+		"attempts": {`
+          retry:
+            attempts: 2
+            interval: 10ms
+          response:
+            until:
+              .body.state: done`,
+			check.StatusFail, 2,
+		},
+		"deadline": {`
+          retry:
+            interval: 10ms
+            deadline: 100ms
+          response:
+            until:
+              .body.state: done`,
+			check.StatusTimeout, 0,
+		},
+	}
+
+	for name, cas := range cases {
+		t.Run(name, func(t *testing.T) {
+			n.Store(0)
+
+			s := run(t, srv.URL, cas.with)
+
+			r := s.Records[0]
+			if r.Status != cas.status {
+				t.Fatalf("got status %q, want %q (%s)", r.Status, cas.status, r.Error)
+			}
+
+			if got := len(r.Attempts); cas.attempts != 0 && got != cas.attempts {
+				t.Errorf("got %d attempts, want %d", got, cas.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryNetwork(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	s := run(t, srv.URL, `
+          retry:
+            attempts: 3
+            interval: 10ms
+            on_network_error: true`)
+
+	if res := s.Results(); len(res) != 1 || res[0].Type != "error" {
+		t.Fatalf("Results()=%+v, want error", res)
+	}
+
+	if got := len(s.Records[0].Attempts); got != 3 {
+		t.Errorf("got %d attempts, want 3", got)
+	}
+}
@@ -0,0 +1,313 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"hookt.dev/cmd/pkg/plugin/builtin/http/wire"
+	protowire "hookt.dev/cmd/pkg/proto/wire"
+)
+
+// payload is the body of a request, held in memory
+// or streamed from a file.
+type payload struct {
+	r           io.Reader
+	p           []byte
+	f           *os.File
+	size        int64
+	contentType string
+}
+
+func validatePayload(req *wire.Request) error {
+	var set []string
+
+	if req.Body != "" {
+		set = append(set, "body")
+	}
+	if len(req.JSON) != 0 {
+		set = append(set, "json")
+	}
+	if len(req.Form) != 0 {
+		set = append(set, "form")
+	}
+	if len(req.Multipart) != 0 {
+		set = append(set, "multipart")
+	}
+	if req.File != "" {
+		set = append(set, "file")
+	}
+
+	if len(set) > 1 {
+		return fmt.Errorf("request: only one of %s may be set", strings.Join(set, ", "))
+	}
+
+	for i, part := range req.Multipart {
+		if part.Value != "" && part.File != "" {
+			return fmt.Errorf("request: multipart[%d]: only one of value and file may be set", i)
+		}
+	}
+
+	return nil
+}
+
+// payload returns the payload of the request, nil when it has no body,
+// evaluating the templates of the json object, form and multipart parts.
+func (s *Step) payload(ctx context.Context, req *wire.Request) (*payload, error) {
+	if err := validatePayload(req); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case req.Body != "":
+		return bytesPayload([]byte(req.Body), ""), nil
+	case len(req.JSON) != 0:
+		p, err := s.json(ctx, req.JSON)
+		if err != nil {
+			return nil, err
+		}
+		return bytesPayload(p, "application/json"), nil
+	case len(req.Form) != 0:
+		var form map[string]any
+		if err := s.p.p.Template(ctx, req.Form, &form); err != nil {
+			return nil, fmt.Errorf("failed to evaluate form: %w", err)
+		}
+
+		values, err := formValues(form)
+		if err != nil {
+			return nil, err
+		}
+		return bytesPayload([]byte(values.Encode()), "application/x-www-form-urlencoded"), nil
+	case len(req.Multipart) != 0:
+		parts := make([]wire.Part, len(req.Multipart))
+		for i := range req.Multipart {
+			if err := s.template(ctx, &req.Multipart[i], &parts[i]); err != nil {
+				return nil, fmt.Errorf("failed to evaluate multipart[%d]: %w", i, err)
+			}
+		}
+		return multipartPayload(parts)
+	case req.File != "":
+		return filePayload(req.File)
+	default:
+		return nil, nil
+	}
+}
+
+// json returns the JSON body, evaluating the templates of
+// its strings, nested ones included.
+func (s *Step) json(ctx context.Context, raw protowire.Generic) ([]byte, error) {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("failed to read json: %w", err)
+	}
+
+	v, err := s.walk(ctx, v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate json: %w", err)
+	}
+
+	return json.Marshal(v)
+}
+
+func (s *Step) walk(ctx context.Context, v any) (any, error) {
+	var err error
+
+	switch v := v.(type) {
+	case map[string]any:
+		for k, x := range v {
+			if v[k], err = s.walk(ctx, x); err != nil {
+				return nil, err
+			}
+		}
+	case []any:
+		for i, x := range v {
+			if v[i], err = s.walk(ctx, x); err != nil {
+				return nil, err
+			}
+		}
+	case string:
+		if !strings.Contains(v, "${{") {
+			return v, nil
+		}
+
+		p, err := s.p.p.EvaluateContext(ctx, v, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return string(p), nil
+	}
+
+	return v, nil
+}
+
+func bytesPayload(p []byte, contentType string) *payload {
+	return &payload{
+		r:           bytes.NewReader(p),
+		p:           p,
+		size:        int64(len(p)),
+		contentType: contentType,
+	}
+}
+
+func filePayload(path string) (*payload, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open request file: %w", err)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to open request file: %w", err)
+	}
+
+	return &payload{
+		r:           f,
+		f:           f,
+		size:        fi.Size(),
+		contentType: contentType(path),
+	}, nil
+}
+
+func multipartPayload(parts []wire.Part) (*payload, error) {
+	var (
+		buf bytes.Buffer
+		w   = multipart.NewWriter(&buf)
+	)
+
+	for _, part := range parts {
+		if part.File == "" {
+			if err := w.WriteField(part.Name, part.Value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := filePart(w, &part); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return bytesPayload(buf.Bytes(), w.FormDataContentType()), nil
+}
+
+func filePart(w *multipart.Writer, part *wire.Part) error {
+	f, err := os.Open(part.File)
+	if err != nil {
+		return fmt.Errorf("failed to open multipart file: %w", err)
+	}
+	defer f.Close()
+
+	var (
+		filename = part.Filename
+		ctype    = part.ContentType
+	)
+
+	if filename == "" {
+		filename = filepath.Base(part.File)
+	}
+
+	if ctype == "" {
+		ctype = contentType(part.File)
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{
+		"name":     part.Name,
+		"filename": filename,
+	}))
+	h.Set("Content-Type", ctype)
+
+	pw, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(pw, f); err != nil {
+		return fmt.Errorf("failed to read multipart file: %w", err)
+	}
+
+	return nil
+}
+
+// formValues returns the values of the form, with strings as is,
+// arrays as repeated values and other values as their JSON text.
+func formValues(form map[string]any) (url.Values, error) {
+	values := make(url.Values, len(form))
+
+	for k, v := range form {
+		a, ok := v.([]any)
+		if !ok {
+			a = []any{v}
+		}
+
+		for _, v := range a {
+			s, err := formValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid form value %q: %w", k, err)
+			}
+			values.Add(k, s)
+		}
+	}
+
+	return values, nil
+}
+
+func formValue(v any) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+
+	p, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return string(p), nil
+}
+
+func contentType(path string) string {
+	if ctype := mime.TypeByExtension(filepath.Ext(path)); ctype != "" {
+		return ctype
+	}
+	return "application/octet-stream"
+}
+
+// bytes returns the payload content, reading the file
+// of a streamed payload into memory.
+func (pl *payload) bytes() ([]byte, error) {
+	if pl.f == nil {
+		return pl.p, nil
+	}
+
+	p, err := io.ReadAll(pl.f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request file: %w", err)
+	}
+
+	pl.f.Close()
+	pl.r, pl.p, pl.f = bytes.NewReader(p), p, nil
+
+	return p, nil
+}
+
+// close closes the file of a payload that was not sent.
+func (pl *payload) close() {
+	if pl != nil && pl.f != nil {
+		pl.f.Close()
+	}
+}
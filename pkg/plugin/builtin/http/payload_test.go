@@ -0,0 +1,212 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"hookt.dev/cmd/pkg/hookt"
+	"hookt.dev/cmd/pkg/proto"
+)
+
+// echoBody responds with the content type and body of the request,
+// along with its form values and the parts of a multipart request.
+func echoBody() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := map[string]any{
+			"content_type": r.Header.Get("Content-Type"),
+			"length":       r.ContentLength,
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+		switch mediaType {
+		case "application/x-www-form-urlencoded":
+			r.ParseForm()
+			resp["form"] = r.PostForm
+		case "multipart/form-data":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			files := make(map[string]any)
+			for name, fhs := range r.MultipartForm.File {
+				f, _ := fhs[0].Open()
+				p, _ := io.ReadAll(f)
+				f.Close()
+
+				files[name] = map[string]string{
+					"filename":     fhs[0].Filename,
+					"content_type": fhs[0].Header.Get("Content-Type"),
+					"content":      string(p),
+				}
+			}
+
+			resp["form"] = r.MultipartForm.Value
+			resp["files"] = files
+		default:
+			p, _ := io.ReadAll(r.Body)
+			resp["body"] = string(p)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestPayload(t *testing.T) {
+	srv := echoBody()
+	defer srv.Close()
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "report.txt"), []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "blob.bin"), []byte{0, 1, 2, 3}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"json": `
+            json:
+              name: ${{ var "name" }}
+              tags: [a, b]
+          response:
+            body:
+              .content_type: application/json
+              .body | fromjson: {"name": "hkt", "tags": ["a", "b"]}`,
+		"json nested": `
+            json:
+              user:
+                name: ${{ var "name" }}
+              tags:
+                - a
+                - ${{ var "name" }}
+          response:
+            body:
+              .body | fromjson: {"user": {"name": "hkt"}, "tags": ["a", "hkt"]}`,
+		"json array": `
+            json:
+              - name: ${{ var "name" }}
+              - ${{ var "name" }}
+          response:
+            body:
+              .content_type: application/json
+              .body | fromjson: [{"name": "hkt"}, "hkt"]`,
+		"form": `
+            form:
+              name: ${{ var "name" }}
+              tag: [a, b]
+              count: 1
+          response:
+            body:
+              .content_type: application/x-www-form-urlencoded
+              .form: {"name": ["hkt"], "tag": ["a", "b"], "count": ["1"]}`,
+		"multipart": `
+            multipart:
+              - name: name
+                value: ${{ var "name" }}
+              - name: report
+                file: ${{ var "dir" }}/report.txt
+              - name: blob
+                file: ${{ var "dir" }}/blob.bin
+                filename: data
+                content_type: application/x-blob
+          response:
+            body:
+              .content_type | startswith("multipart/form-data; boundary="): true
+              .form.name: [hkt]
+              .files.report.filename: report.txt
+              .files.report.content_type | startswith("text/plain"): true
+              .files.report.content: hello
+              .files.blob.filename: data
+              .files.blob.content_type: application/x-blob`,
+		"file": `
+            file: ${{ var "dir" }}/blob.bin
+          response:
+            body:
+              .content_type: application/octet-stream
+              .length: 4
+              .body | explode: [0, 1, 2, 3]`,
+		"header": `
+            headers:
+              Content-Type: application/vnd.hkt+json
+            json:
+              name: ${{ var "name" }}
+          response:
+            body:
+              .content_type: application/vnd.hkt+json`,
+	}
+
+	for name, with := range cases {
+		t.Run(name, func(t *testing.T) {
+			workflow := `
+jobs:
+  - id: payload
+    plugins:
+      - uses: http
+        with:
+          headers:
+            Content-Type: text/plain
+    steps:
+      - uses: http
+        with:
+          request:
+            method: POST
+            url: ${{ var "url" }}` + with
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ngn := hookt.New(hookt.WithProtoOptions(proto.WithVars(map[string]any{
+				"url":  srv.URL,
+				"dir":  dir,
+				"name": "hkt",
+			})))
+
+			s, err := ngn.Run(ctx, []byte(workflow))
+			if err != nil {
+				t.Fatalf("Run()=%v", err)
+			}
+
+			if res := s.Results(); len(res) != 0 {
+				t.Fatalf("Results()=%+v", res)
+			}
+		})
+	}
+}
+
+func TestPayloadValidate(t *testing.T) {
+	const workflow = `
+jobs:
+  - id: payload
+    plugins:
+      - uses: http
+        with:
+          timeout: 5s
+    steps:
+      - uses: http
+        with:
+          request:
+            url: http://localhost
+            body: hi
+            form:
+              name: hkt
+`
+
+	err := hookt.New().Validate(context.Background(), []byte(workflow))
+	if err == nil || !strings.Contains(err.Error(), "only one of body, form may be set") {
+		t.Fatalf("Validate()=%v, want error", err)
+	}
+}
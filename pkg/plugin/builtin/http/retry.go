@@ -0,0 +1,200 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"hookt.dev/cmd/pkg/check"
+	"hookt.dev/cmd/pkg/plugin/builtin/http/wire"
+)
+
+const (
+	DefaultAttempts = 3
+	DefaultInterval = time.Second
+)
+
+type retry struct {
+	attempts    int // 0 for no limit
+	interval    time.Duration
+	maxInterval time.Duration
+	backoff     float64
+	deadline    time.Duration
+	status      []int
+	network     bool
+}
+
+// makeRetry returns the retry of the step; a step without retry
+// makes a single attempt, unless it has until patterns.
+func makeRetry(raw *wire.Retry, until bool) (*retry, error) {
+	if raw == nil {
+		if !until {
+			return &retry{attempts: 1}, nil
+		}
+		raw = &wire.Retry{}
+	}
+
+	rt := &retry{
+		attempts: raw.Attempts,
+		interval: DefaultInterval,
+		backoff:  raw.Backoff,
+		status:   raw.OnStatus,
+		network:  raw.OnNetworkError,
+	}
+
+	var err error
+
+	if raw.Attempts < 0 {
+		return nil, fmt.Errorf("invalid retry attempts %d", raw.Attempts)
+	}
+
+	if raw.Backoff < 0 {
+		return nil, fmt.Errorf("invalid retry backoff %v", raw.Backoff)
+	}
+
+	if raw.Interval != "" {
+		if rt.interval, err = time.ParseDuration(raw.Interval); err != nil {
+			return nil, fmt.Errorf("invalid retry interval %q: %w", raw.Interval, err)
+		}
+	}
+
+	if raw.MaxInterval != "" {
+		if rt.maxInterval, err = time.ParseDuration(raw.MaxInterval); err != nil {
+			return nil, fmt.Errorf("invalid retry max interval %q: %w", raw.MaxInterval, err)
+		}
+	}
+
+	if raw.Deadline != "" {
+		if rt.deadline, err = time.ParseDuration(raw.Deadline); err != nil {
+			return nil, fmt.Errorf("invalid retry deadline %q: %w", raw.Deadline, err)
+		}
+	}
+
+	if rt.attempts == 0 && rt.deadline == 0 {
+		rt.attempts = DefaultAttempts
+	}
+
+	return rt, nil
+}
+
+// next returns the interval to wait after the nth attempt.
+func (rt *retry) next(n int) time.Duration {
+	d := rt.interval
+
+	if rt.backoff > 0 {
+		for i := 1; i < n; i++ {
+			d = time.Duration(float64(d) * rt.backoff)
+
+			if rt.maxInterval > 0 && d >= rt.maxInterval {
+				break
+			}
+		}
+	}
+
+	if rt.maxInterval > 0 && d > rt.maxInterval {
+		d = rt.maxInterval
+	}
+
+	return d
+}
+
+// retryError is returned by attempts that may be retried.
+type retryError struct {
+	err error
+}
+
+func (e *retryError) Error() string { return e.err.Error() }
+
+func (e *retryError) Unwrap() error { return e.err }
+
+// retry runs the attempts of the step, recording each one in s
+// when the step may make more than one.
+func (s *Step) retry(ctx context.Context, cs *check.S, rt *retry, sns *sensor) error {
+	if rt.deadline != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rt.deadline)
+		defer cancel()
+	}
+
+	for n := 1; ; n++ {
+		start := time.Now()
+
+		err := s.attempt(ctx, rt, sns)
+
+		if rt.attempts != 1 && cs != nil {
+			cs.Attempt(ctx, start, err)
+		}
+
+		var re *retryError
+		if !errors.As(err, &re) {
+			return err
+		}
+
+		if rt.attempts != 0 && n >= rt.attempts {
+			return fmt.Errorf("gave up after %d attempts: %w", n, re.err)
+		}
+
+		d := rt.next(n)
+
+		slog.Debug("http: retry",
+			"attempt", n,
+			"interval", d,
+			"reason", re.err,
+		)
+
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return fmt.Errorf("%w after %d attempts: %w", ctx.Err(), n, re.err)
+		}
+	}
+}
+
+// attempt sends the request and checks the response, returning
+// a retryError when it may be retried.
+func (s *Step) attempt(ctx context.Context, rt *retry, sns *sensor) error {
+	req, err := s.req(ctx, &s.Request)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.p.c.Do(req)
+	if err != nil {
+		if rt.network && ctx.Err() == nil {
+			return &retryError{err: err}
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		s.p.a.invalidate()
+	}
+
+	r, err := makeResponse(resp)
+	if err != nil {
+		return err
+	}
+
+	s.r = r
+
+	obj := r.Object()
+
+	if slices.Contains(rt.status, r.Status) {
+		return &retryError{err: fmt.Errorf("response has status %d", r.Status)}
+	}
+
+	ok, err := sns.until.Match(group(ctx, "until"), obj)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &retryError{err: errors.New("response did not match until pattern")}
+	}
+
+	return sns.do(ctx, obj)
+}
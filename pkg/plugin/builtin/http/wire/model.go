@@ -10,9 +10,75 @@ import (
 	"hookt.dev/cmd/pkg/proto/wire"
 )
 
+// Config configures the client shared by the steps of the plugin.
+//
+// Proxy is the URL of the proxy requests are sent through, instead of
+// the one from the environment. HTTP2 is attempted unless disabled.
+// Redirects is the maximum number of redirects followed, 10 by default,
+// after which the redirect response is returned.
 type Config struct {
-	Timeout string      `json:"timeout,omitempty"`
-	Headers wire.Object `json:"headers,omitempty"`
+	Timeout   string      `json:"timeout,omitempty"`
+	Headers   wire.Object `json:"headers,omitempty"`
+	Auth      *Auth       `json:"auth,omitempty"`
+	TLS       *TLS        `json:"tls,omitempty"`
+	Proxy     string      `json:"proxy,omitempty"`
+	HTTP2     *bool       `json:"http2,omitempty"`
+	Redirects *int        `json:"redirects,omitempty"`
+}
+
+// Auth authenticates the requests with one of basic, bearer and oauth2,
+// and signs them when hmac is set. Headers of the step take precedence.
+type Auth struct {
+	Basic  *Basic  `json:"basic,omitempty"`
+	Bearer string  `json:"bearer,omitempty"`
+	OAuth2 *OAuth2 `json:"oauth2,omitempty"`
+	HMAC   *HMAC   `json:"hmac,omitempty"`
+}
+
+type Basic struct {
+	Username string `json:"username" jsonschema:"required"`
+	Password string `json:"password,omitempty"`
+}
+
+// OAuth2 gets tokens from the token URL with the client credentials
+// grant, sending Params along with the scopes. A token is reused until
+// it expires or a response has status 401.
+type OAuth2 struct {
+	TokenURL     string            `json:"token_url" jsonschema:"required"`
+	ClientID     string            `json:"client_id" jsonschema:"required"`
+	ClientSecret string            `json:"client_secret,omitempty"`
+	Scopes       []string          `json:"scopes,omitempty"`
+	Params       map[string]string `json:"params,omitempty"`
+}
+
+// HMAC sets the Header, X-Signature by default, to the signature of the
+// request body, prefixed with Prefix, e.g. sha256=. The algorithm is one
+// of sha1, sha256, the default, and sha512, and the encoding one of hex,
+// the default, and base64.
+//
+// With a TimestampHeader, the request is sent with the current Unix time
+// in it, and the signature is the one of the timestamp and the body
+// joined with a dot.
+type HMAC struct {
+	Secret          string `json:"secret" jsonschema:"required"`
+	Algorithm       string `json:"algorithm,omitempty"`
+	Encoding        string `json:"encoding,omitempty"`
+	Header          string `json:"header,omitempty"`
+	Prefix          string `json:"prefix,omitempty"`
+	TimestampHeader string `json:"timestamp_header,omitempty"`
+}
+
+// TLS configures the client TLS. CA, Cert and Key are paths to PEM
+// files: CA holds the certificates trusted in addition to the system
+// ones, Cert and Key the client certificate for mutual TLS. ServerName
+// overrides the name sent with SNI and checked against the server
+// certificate.
+type TLS struct {
+	CA                 string `json:"ca,omitempty"`
+	Cert               string `json:"cert,omitempty"`
+	Key                string `json:"key,omitempty"`
+	ServerName         string `json:"server_name,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
 }
 
 func (c Config) GetTimeout() time.Duration {
@@ -29,9 +95,9 @@ func (c Config) GetTimeout() time.Duration {
 	return d
 }
 
-func Headers(raw wire.Object, p *proto.P) (http.Header, error) {
+func Headers(ctx context.Context, raw wire.Object, p *proto.P) (http.Header, error) {
 	var m map[string]string
-	if err := p.Template(context.TODO(), raw, &m); err != nil {
+	if err := p.Template(ctx, raw, &m); err != nil {
 		return nil, err
 	}
 	h := http.Header{}
@@ -44,15 +110,72 @@ func Headers(raw wire.Object, p *proto.P) (http.Header, error) {
 type Step struct {
 	Request  Request  `json:"request"`
 	Response Response `json:"response"`
+	Retry    *Retry   `json:"retry,omitempty"`
+}
+
+// Retry configures re-issuing the request after a response status
+// listed in OnStatus or a network error when OnNetworkError is set,
+// and while the response does not match the until patterns.
+//
+// Attempts default to 3, or to no limit when a deadline is set;
+// the interval, 1s by default, is multiplied by the backoff after
+// every attempt, up to the max interval.
+type Retry struct {
+	Attempts    int     `json:"attempts,omitempty"`
+	Interval    string  `json:"interval,omitempty"`
+	Backoff     float64 `json:"backoff,omitempty"`
+	MaxInterval string  `json:"max_interval,omitempty"`
+	Deadline    string  `json:"deadline,omitempty"`
+
+	OnStatus       []int `json:"on_status,omitempty"`
+	OnNetworkError bool  `json:"on_network_error,omitempty"`
 }
 
+// Request is the request of the step, with at most one of its body
+// variants: Body is sent as is, JSON marshaled, Form urlencoded, with
+// arrays as repeated values, Multipart sent as multipart/form-data and
+// File streamed from the file at its path. But for Body, the variants
+// set the Content-Type, unless set by the step headers. When the auth
+// of the plugin signs requests with hmac, a File is read fully into
+// memory to be signed instead of being streamed.
 type Request struct {
-	Method  string      `json:"method,omitempty"`
-	URL     string      `json:"url"`
-	Headers wire.Object `json:"headers,omitempty"`
-	Body    string      `json:"body,omitempty"`
+	Method    string       `json:"method,omitempty"`
+	URL       string       `json:"url" jsonschema:"required"`
+	Headers   wire.Object  `json:"headers,omitempty"`
+	Body      string       `json:"body,omitempty"`
+	JSON      wire.Generic `json:"json,omitempty"`
+	Form      wire.Object  `json:"form,omitempty"`
+	Multipart []Part       `json:"multipart,omitempty"`
+	File      string       `json:"file,omitempty"`
+}
+
+// Part is a part of a multipart body, with either a Value or the
+// content of a File, sent with its base name unless Filename is set.
+// The content type of files is guessed from their extension unless
+// ContentType is set.
+type Part struct {
+	Name        string `json:"name" jsonschema:"required"`
+	Value       string `json:"value,omitempty"`
+	File        string `json:"file,omitempty"`
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
 }
 
+// Response holds the patterns the response is checked against, as
+// an object with status, header, body, raw and content_type keys.
+//
+// Status, Headers and Body are shorthands for pass patterns on
+// .status, on .header[name] and on .body | key respectively.
+//
+// Until patterns make the step re-issue the request until they
+// pass, as configured by the step retry, before checking the others.
 type Response struct {
-	Pass wire.Object `json:"pass,omitempty"`
+	Status  wire.Generic `json:"status,omitempty"`
+	Headers wire.Object  `json:"headers,omitempty"`
+	Body    wire.Object  `json:"body,omitempty"`
+
+	Until wire.Object `json:"until,omitempty"`
+	Match wire.Object `json:"match,omitempty"`
+	Pass  wire.Object `json:"pass,omitempty"`
+	Fail  wire.Object `json:"fail,omitempty"`
 }
@@ -9,6 +9,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 
 	"hookt.dev/cmd/pkg/plugin/builtin/inline/wire"
 	"hookt.dev/cmd/pkg/proto"
@@ -21,17 +22,28 @@ import (
 type Plugin struct {
 	wire.Config
 
-	p *proto.P
-	c chan proto.Message
+	p    *proto.P
+	c    chan proto.Message
+	done chan struct{}
+	once sync.Once
 }
 
 func (p *Plugin) Name() string {
 	return "inline"
 }
 
+func (p *Plugin) Describe() (config, step any) {
+	return wire.Config{}, wire.Step{}
+}
+
+func (p *Plugin) Description() string {
+	return "Publishes JSON messages read from a file"
+}
+
 func New(opts ...func(*Plugin)) *Plugin {
 	p := &Plugin{
-		c: make(chan proto.Message),
+		c:    make(chan proto.Message),
+		done: make(chan struct{}),
 	}
 	for _, opt := range opts {
 		opt(p)
@@ -45,7 +57,7 @@ func (p *Plugin) WithProto(q *proto.P) *Plugin {
 }
 
 func (p *Plugin) Plugin(_ context.Context, q *proto.P) any {
-	return p.WithProto(q)
+	return New().WithProto(q)
 }
 
 func (p *Plugin) Init(ctx context.Context, _ *proto.Job) error {
@@ -53,7 +65,7 @@ func (p *Plugin) Init(ctx context.Context, _ *proto.Job) error {
 		"config", p.Config,
 	)
 
-	file, err := p.p.Evaluate(p.Config.Publish.File, nil)
+	file, err := p.p.EvaluateContext(ctx, p.Config.Publish.File, nil)
 	if err != nil {
 		return err
 	}
@@ -75,10 +87,7 @@ func (p *Plugin) Init(ctx context.Context, _ *proto.Job) error {
 func (p *Plugin) publish(ctx context.Context, f *os.File) {
 	defer f.Close()
 
-	var (
-		dec = json.NewDecoder(f)
-		tr  = trace.ContextSchedule(ctx)
-	)
+	dec := json.NewDecoder(f)
 
 	for index := 0; ; index++ {
 		var raw json.RawMessage
@@ -106,9 +115,9 @@ func (p *Plugin) publish(ctx context.Context, f *os.File) {
 
 			msg := &protowire.Message{P: raw, I: index}
 
-			tr.BeforePublish(ctx, msg)
-			p.c <- msg
-			tr.Publish(ctx, msg)
+			if !p.send(ctx, msg) {
+				return
+			}
 		case '[':
 			var msgs []json.RawMessage
 
@@ -127,9 +136,9 @@ func (p *Plugin) publish(ctx context.Context, f *os.File) {
 
 				msg := &protowire.Message{P: msgs[i], I: index}
 
-				tr.BeforePublish(ctx, msg)
-				p.c <- msg
-				tr.Publish(ctx, msg)
+				if !p.send(ctx, msg) {
+					return
+				}
 			}
 		default:
 			err = errors.New("unexpected JSON input")
@@ -145,6 +154,27 @@ func (p *Plugin) publish(ctx context.Context, f *os.File) {
 	}
 }
 
+// send publishes msg, reporting false when the plugin was
+// closed before the message was received.
+func (p *Plugin) send(ctx context.Context, msg *protowire.Message) bool {
+	tr := trace.ContextSchedule(ctx)
+
+	tr.BeforePublish(ctx, msg)
+	select {
+	case p.c <- msg:
+		tr.Publish(ctx, msg)
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// Close stops publishing, closing the file being read.
+func (p *Plugin) Close(context.Context) error {
+	p.once.Do(func() { close(p.done) })
+	return nil
+}
+
 func (p *Plugin) Subscribe(context.Context) <-chan proto.Message {
 	return p.c
 }
@@ -2,25 +2,53 @@ package nats // import "hookt.dev/cmd/pkg/plugin/builtin/nats"
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
 
 	"hookt.dev/cmd/pkg/check"
+	"hookt.dev/cmd/pkg/errors"
 	"hookt.dev/cmd/pkg/plugin/builtin/nats/wire"
 	"hookt.dev/cmd/pkg/proto"
+	protowire "hookt.dev/cmd/pkg/proto/wire"
+	"hookt.dev/cmd/pkg/trace"
+
+	"github.com/lmittmann/tint"
+	"github.com/nats-io/nats.go"
+	"sigs.k8s.io/yaml"
 )
 
 type Plugin struct {
 	wire.Config
 
-	p *proto.P
+	p    *proto.P
+	nc   *nats.Conn
+	c    chan proto.Message
+	sub  atomic.Bool
+	seq  atomic.Int64
+	done chan struct{}
+	once sync.Once
 }
 
 func (p *Plugin) Name() string {
 	return "nats"
 }
 
+func (p *Plugin) Describe() (config, step any) {
+	return wire.Config{}, wire.Step{}
+}
+
+func (p *Plugin) Description() string {
+	return "Publishes and receives messages on NATS subjects"
+}
+
 func New(opts ...func(*Plugin)) *Plugin {
-	p := &Plugin{}
+	p := &Plugin{
+		c:    make(chan proto.Message),
+		done: make(chan struct{}),
+	}
 	for _, opt := range opts {
 		opt(p)
 	}
@@ -33,17 +61,141 @@ func (p *Plugin) WithProto(q *proto.P) *Plugin {
 }
 
 func (p *Plugin) Plugin(_ context.Context, q *proto.P) any {
-	return p.WithProto(q)
+	return New().WithProto(q)
 }
 
-func (p *Plugin) Init(_ context.Context, job *proto.Job) error {
+func (p *Plugin) Init(ctx context.Context, job *proto.Job) error {
 	slog.Debug("nats: init",
 		"config", p.Config,
 	)
 
+	url, err := p.p.EvaluateContext(ctx, p.Config.URL, nil)
+	if err != nil {
+		return errors.New("failed to evaluate url: %w", err)
+	}
+
+	if len(url) == 0 {
+		url = []byte(nats.DefaultURL)
+	}
+
+	creds, err := p.p.EvaluateContext(ctx, p.Config.Credentials, nil)
+	if err != nil {
+		return errors.New("failed to evaluate credentials: %w", err)
+	}
+
+	opts := []nats.Option{
+		nats.Name("hkt"),
+	}
+
+	if len(creds) != 0 {
+		opts = append(opts, nats.UserCredentials(string(creds)))
+	}
+
+	p.nc, err = nats.Connect(string(url), opts...)
+	if err != nil {
+		return errors.New("failed to connect to %q: %w", url, err)
+	}
+
+	if sub := p.Config.Subscribe; sub != nil {
+		if _, err := p.nc.Subscribe(sub.Subject, p.receive(ctx)); err != nil {
+			p.nc.Close()
+			return errors.New("failed to subscribe to %q: %w", sub.Subject, err)
+		}
+	}
+
+	go func() {
+		<-ctx.Done()
+		p.nc.Close()
+	}()
+
+	return nil
+}
+
+// Close stops publishing messages and closes the connection.
+func (p *Plugin) Close(context.Context) error {
+	p.once.Do(func() {
+		close(p.done)
+
+		if p.nc != nil {
+			p.nc.Close()
+		}
+	})
+
 	return nil
 }
 
+func (p *Plugin) Subscribe(context.Context) <-chan proto.Message {
+	p.sub.Store(true)
+	return p.c
+}
+
+func (p *Plugin) receive(ctx context.Context) nats.MsgHandler {
+	tr := trace.ContextSchedule(ctx)
+
+	return func(m *nats.Msg) {
+		if !p.sub.Load() {
+			return
+		}
+
+		q, err := json.Marshal(makeMessage(m))
+		if err != nil {
+			slog.Error("nats: receive",
+				"subject", m.Subject,
+				tint.Err(err),
+			)
+			return
+		}
+
+		var (
+			index = int(p.seq.Add(1) - 1)
+			msg   = &protowire.Message{P: q, I: index}
+			ctx   = trace.With(ctx, "event-seq", strconv.Itoa(index))
+		)
+
+		slog.Debug("nats: publish",
+			"subject", m.Subject,
+			"bytes", len(q),
+		)
+
+		tr.BeforePublish(ctx, msg)
+		select {
+		case p.c <- msg:
+			tr.Publish(ctx, msg)
+		case <-ctx.Done():
+		case <-p.done:
+		}
+	}
+}
+
+// Message is the object published for every message received
+// on the subscribed subject.
+type Message struct {
+	Subject string            `json:"subject"`
+	Reply   string            `json:"reply,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Data    any               `json:"data,omitempty"`
+}
+
+func makeMessage(m *nats.Msg) *Message {
+	msg := &Message{
+		Subject: m.Subject,
+		Reply:   m.Reply,
+	}
+
+	if len(m.Header) != 0 {
+		msg.Headers = make(map[string]string, len(m.Header))
+		for k := range m.Header {
+			msg.Headers[k] = m.Header.Get(k)
+		}
+	}
+
+	if err := json.Unmarshal(m.Data, &msg.Data); err != nil {
+		msg.Data = string(m.Data)
+	}
+
+	return msg
+}
+
 func (p *Plugin) Step(context.Context) any {
 	return &Step{p: p}
 }
@@ -54,8 +206,54 @@ type Step struct {
 	p *Plugin
 }
 
-func (s *Step) Run(_ context.Context, _ *check.S) error {
+func (s *Step) Run(ctx context.Context, _ *check.S) error {
+	pub := s.Step.Publish
+	if pub == nil {
+		return nil
+	}
+
+	subject, err := s.p.p.EvaluateContext(ctx, pub.Subject, nil)
+	if err != nil {
+		return errors.New("failed to evaluate subject: %w", err)
+	}
+
+	var data map[string]any
+
+	if err := s.p.p.Template(ctx, pub.Data, &data); err != nil {
+		return errors.New("failed to evaluate data: %w", err)
+	}
+
+	p, err := encode(pub.Encoding, data)
+	if err != nil {
+		return err
+	}
+
+	slog.Debug("nats: publish",
+		"subject", string(subject),
+		"encoding", pub.Encoding,
+		"bytes", len(p),
+	)
+
+	if err := s.p.nc.Publish(string(subject), p); err != nil {
+		return errors.New("failed to publish to %q: %w", subject, err)
+	}
+
+	if err := s.p.nc.FlushWithContext(ctx); err != nil {
+		return errors.New("failed to flush: %w", err)
+	}
+
 	return nil
 }
 
-func (s *Step) Stop() {}
+func (s *Step) Stop(context.Context) {}
+
+func encode(encoding string, v any) ([]byte, error) {
+	switch encoding {
+	case "", "json":
+		return json.Marshal(v)
+	case "yaml":
+		return yaml.Marshal(v)
+	default:
+		return nil, errors.New("unsupported encoding %q", encoding)
+	}
+}
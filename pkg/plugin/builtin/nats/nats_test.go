@@ -0,0 +1,70 @@
+package nats_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"hookt.dev/cmd/pkg/hookt"
+	"hookt.dev/cmd/pkg/internal/testutil"
+)
+
+const workflow = `
+jobs:
+  - id: nats
+    plugins:
+      - id: bus
+        uses: nats
+        with:
+          url: ${{ env "NATS_URL" }}
+          credentials: ""
+          subscribe:
+            subject: example.>
+      - uses: event
+        with:
+          sources:
+          - bus
+          inactive_timeout: 5s
+    steps:
+      - uses: event
+        with:
+          match:
+            .subject: example.json
+          pass:
+            .data.message: hi
+      - uses: event
+        with:
+          match:
+            .subject: example.yaml
+          pass:
+            '.data | startswith("message: hi")': true
+      - uses: nats
+        with:
+          publish:
+            subject: example.json
+            data:
+              message: hi
+      - uses: nats
+        with:
+          publish:
+            subject: example.yaml
+            encoding: yaml
+            data:
+              message: hi
+`
+
+func TestNats(t *testing.T) {
+	t.Setenv("NATS_URL", testutil.NATSServer(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s, err := hookt.New().Run(ctx, []byte(workflow))
+	if err != nil {
+		t.Fatalf("Run()=%+v", err)
+	}
+
+	if res := s.Results(); len(res) != 0 {
+		t.Fatalf("Results()=%+v", res)
+	}
+}
@@ -1,16 +1,24 @@
 package wire // import "hookt.dev/cmd/pkg/plugin/builtin/nats/wire"
 
 import (
+	"encoding/json"
+
 	"hookt.dev/cmd/pkg/proto/wire"
 )
 
 type Config struct {
+	URL         string        `json:"url,omitempty"`
 	Credentials string        `json:"credentials"`
 	Subscribe   *Subscription `json:"subscribe"`
 }
 
+func (c Config) String() string {
+	p, _ := json.Marshal(c)
+	return string(p)
+}
+
 type Subscription struct {
-	Subject string `json:"subject"`
+	Subject string `json:"subject" jsonschema:"required"`
 }
 
 type Step struct {
@@ -18,7 +26,7 @@ type Step struct {
 }
 
 type Message struct {
-	Subject  string      `json:"subject"`
+	Subject  string      `json:"subject" jsonschema:"required"`
 	Encoding string      `json:"encoding,omitempty"`
 	Data     wire.Object `json:"data"`
 }
@@ -2,25 +2,56 @@ package webhook // import "hookt.dev/cmd/pkg/plugin/builtin/webhook"
 
 import (
 	"context"
+	"encoding/json"
+	"io"
 	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"hookt.dev/cmd/pkg/check"
+	"hookt.dev/cmd/pkg/errors"
 	"hookt.dev/cmd/pkg/plugin/builtin/webhook/wire"
 	"hookt.dev/cmd/pkg/proto"
+	protowire "hookt.dev/cmd/pkg/proto/wire"
+	"hookt.dev/cmd/pkg/trace"
+
+	"github.com/lmittmann/tint"
+	"sigs.k8s.io/yaml"
 )
 
 type Plugin struct {
 	wire.Config
 
-	p *proto.P
+	p    *proto.P
+	c    chan proto.Message
+	sub  atomic.Bool
+	seq  atomic.Int64
+	srv  *http.Server
+	done chan struct{}
+	once sync.Once
 }
 
 func (p *Plugin) Name() string {
 	return "webhook"
 }
 
+func (p *Plugin) Describe() (config, step any) {
+	return wire.Config{}, wire.Step{}
+}
+
+func (p *Plugin) Description() string {
+	return "Serves HTTP endpoints and publishes the requests they receive"
+}
+
 func New(opts ...func(*Plugin)) *Plugin {
-	p := &Plugin{}
+	p := &Plugin{
+		c:    make(chan proto.Message),
+		done: make(chan struct{}),
+	}
 	for _, opt := range opts {
 		opt(p)
 	}
@@ -32,16 +63,382 @@ func (p *Plugin) WithProto(q *proto.P) *Plugin {
 	return p
 }
 
-func (p *Plugin) Init(_ context.Context, job *proto.Job) error {
+func (p *Plugin) Init(ctx context.Context, job *proto.Job) error {
 	slog.Debug("webhook: init",
 		"config", p.Config,
 	)
 
+	ln, err := net.Listen("tcp", p.Config.GetListen())
+	if err != nil {
+		return errors.New("failed to listen on %q: %w", p.Config.GetListen(), err)
+	}
+
+	var (
+		routes = make(map[string]http.HandlerFunc, len(p.Config.Endpoints))
+		base   = "http://" + ln.Addr().String()
+	)
+
+	for path, raw := range p.Config.Endpoints {
+		if !strings.HasPrefix(path, "/") {
+			ln.Close()
+			return errors.New("invalid endpoint %q: path must start with /", path)
+		}
+
+		if _, err := p.eval(ctx, raw, base+path); err != nil {
+			ln.Close()
+			return errors.New("failed to evaluate endpoint %q: %w", path, err)
+		}
+
+		slog.Debug("webhook: endpoint",
+			"path", path,
+			"url", base+path,
+		)
+
+		routes[path] = p.serve(trace.With(ctx, "endpoint", path))
+	}
+
+	p.srv = &http.Server{
+		Handler: route(routes),
+	}
+
+	go func() {
+		if err := p.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("webhook: serve",
+				"addr", base,
+				tint.Err(err),
+			)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		p.srv.Close()
+	}()
+
 	return nil
 }
 
+// Close stops publishing requests and shuts the server down,
+// waiting for active requests until ctx is done.
+func (p *Plugin) Close(ctx context.Context) error {
+	var err error
+
+	p.once.Do(func() {
+		close(p.done)
+
+		if p.srv != nil {
+			err = p.srv.Shutdown(ctx)
+		}
+	})
+
+	return err
+}
+
 func (p *Plugin) Plugin(_ context.Context, q *proto.P) any {
-	return p.WithProto(q)
+	return New().WithProto(q)
+}
+
+func (p *Plugin) Subscribe(context.Context) <-chan proto.Message {
+	p.sub.Store(true)
+	return p.c
+}
+
+// route serves each request by the endpoint whose path is equal to
+// the request path. Paths are not http.ServeMux patterns, so braces,
+// spaces or methods in them are matched literally.
+func route(routes map[string]http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serve, ok := routes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		serve(w, r)
+	}
+}
+
+func (p *Plugin) serve(ctx context.Context) http.HandlerFunc {
+	tr := trace.ContextSchedule(ctx)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req := makeRequest(r, body)
+
+		ok, err := p.allow(r.Context(), req)
+		if err != nil {
+			slog.Error("webhook: respond",
+				"path", req.Path,
+				tint.Err(err),
+			)
+
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		resp, err := p.respond(r.Context(), req)
+		if err != nil {
+			slog.Error("webhook: respond",
+				"path", req.Path,
+				tint.Err(err),
+			)
+
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if p.sub.Load() {
+			q, err := json.Marshal(req)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			var (
+				index = int(p.seq.Add(1) - 1)
+				msg   = &protowire.Message{P: q, I: index}
+				ctx   = trace.With(ctx, "event-seq", strconv.Itoa(index))
+			)
+
+			slog.Debug("webhook: publish",
+				"path", req.Path,
+				"bytes", len(q),
+			)
+
+			tr.BeforePublish(ctx, msg)
+			select {
+			case p.c <- msg:
+				tr.Publish(ctx, msg)
+			case <-r.Context().Done():
+				return
+			case <-ctx.Done():
+				http.Error(w, ctx.Err().Error(), http.StatusServiceUnavailable)
+				return
+			case <-p.done:
+				http.Error(w, "webhook closed", http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		for k, v := range resp.Headers {
+			w.Header().Set(k, v)
+		}
+
+		w.WriteHeader(resp.Status)
+		w.Write(resp.Body)
+	}
+}
+
+// Request is the object published for every request received
+// by a webhook endpoint.
+type Request struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Query   map[string]string `json:"query,omitempty"`
+	Headers Header            `json:"headers,omitempty"`
+	Body    any               `json:"body,omitempty"`
+
+	raw []byte
+}
+
+func makeRequest(r *http.Request, body []byte) *Request {
+	req := &Request{
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Query:   make(map[string]string),
+		Headers: make(Header),
+		raw:     body,
+	}
+
+	for k := range r.URL.Query() {
+		req.Query[k] = r.URL.Query().Get(k)
+	}
+
+	for k := range r.Header {
+		req.Headers[k] = r.Header.Get(k)
+	}
+
+	if err := json.Unmarshal(body, &req.Body); err != nil {
+		req.Body = string(body)
+	}
+
+	return req
+}
+
+// Header renders as a JSON object, so ${{ . }} echoes
+// the request headers in a form that can be read back.
+type Header map[string]string
+
+func (h Header) String() string {
+	p, _ := json.Marshal(h)
+	return string(p)
+}
+
+type response struct {
+	Status  int
+	Headers map[string]string
+	Body    []byte
+}
+
+// allow reports whether the request method is the one the Do handler
+// accepts; requests with another method are neither answered by the
+// handler nor published.
+func (p *Plugin) allow(ctx context.Context, req *Request) (bool, error) {
+	do := p.Config.Do
+	if do == nil {
+		return true, nil
+	}
+
+	method, err := p.eval(ctx, do.Method, req.Method)
+	if err != nil {
+		return false, errors.New("failed to evaluate method: %w", err)
+	}
+
+	if method, ok := method.(string); ok && method != "" && !strings.EqualFold(method, req.Method) {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// respond evaluates the Do handler against the request: status sees
+// the whole request, while headers and body each see the matching
+// part of it.
+func (p *Plugin) respond(ctx context.Context, req *Request) (*response, error) {
+	resp := &response{
+		Status:  http.StatusOK,
+		Headers: make(map[string]string),
+	}
+
+	do := p.Config.Do
+	if do == nil {
+		return resp, nil
+	}
+
+	status, err := p.eval(ctx, do.Status, req)
+	if err != nil {
+		return nil, errors.New("failed to evaluate status: %w", err)
+	}
+
+	switch status := status.(type) {
+	case nil:
+	case float64:
+		resp.Status = int(status)
+	case string:
+		if resp.Status, err = strconv.Atoi(strings.TrimSpace(status)); err != nil {
+			return nil, errors.New("invalid status %q: %w", status, err)
+		}
+	default:
+		return nil, errors.New("invalid status: %v", status)
+	}
+
+	if err := p.headers(ctx, do.Headers, req.Headers, resp.Headers); err != nil {
+		return nil, errors.New("failed to evaluate headers: %w", err)
+	}
+
+	body, err := p.eval(ctx, do.Body, string(req.raw))
+	if err != nil {
+		return nil, errors.New("failed to evaluate body: %w", err)
+	}
+
+	switch body := body.(type) {
+	case nil:
+	case string:
+		resp.Body = []byte(body)
+	default:
+		if resp.Body, err = json.Marshal(body); err != nil {
+			return nil, errors.New("failed to marshal body: %w", err)
+		}
+		if _, ok := resp.Headers["Content-Type"]; !ok {
+			resp.Headers["Content-Type"] = "application/json"
+		}
+	}
+
+	return resp, nil
+}
+
+func (p *Plugin) headers(ctx context.Context, raw protowire.Generic, data Header, out map[string]string) error {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var v any
+
+	if err := yaml.Unmarshal(raw, &v); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case string:
+		q, err := p.p.EvaluateContext(ctx, v, data)
+		if err != nil {
+			return err
+		}
+
+		if err := yaml.Unmarshal(q, &out); err != nil {
+			return errors.New("failed to parse headers %q: %w", q, err)
+		}
+	case map[string]any:
+		for k, v := range v {
+			s, ok := v.(string)
+			if !ok {
+				out[k] = str(v)
+				continue
+			}
+
+			q, err := p.p.EvaluateContext(ctx, s, data)
+			if err != nil {
+				return err
+			}
+
+			out[k] = string(q)
+		}
+	case nil:
+	default:
+		return errors.New("unexpected headers type %T", v)
+	}
+
+	return nil
+}
+
+// eval unmarshals raw value and, if it is a string, evaluates
+// it as a template against data.
+func (p *Plugin) eval(ctx context.Context, raw protowire.Generic, data any) (any, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var v any
+
+	if err := yaml.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+
+	q, err := p.p.EvaluateContext(ctx, s, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(q), nil
+}
+
+func str(v any) string {
+	p, _ := json.Marshal(v)
+	return string(p)
 }
 
 func (p *Plugin) Step(context.Context) any {
@@ -58,4 +455,4 @@ func (s *Step) Run(_ context.Context, _ *check.S) error {
 	return nil
 }
 
-func (s *Step) Stop() {}
+func (s *Step) Stop(context.Context) {}
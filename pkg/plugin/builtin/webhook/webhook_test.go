@@ -0,0 +1,229 @@
+package webhook_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"hookt.dev/cmd/pkg/hookt"
+	"hookt.dev/cmd/pkg/plugin/builtin/webhook"
+	"hookt.dev/cmd/pkg/plugin/builtin/webhook/wire"
+	"hookt.dev/cmd/pkg/proto"
+	protowire "hookt.dev/cmd/pkg/proto/wire"
+)
+
+const workflow = `
+jobs:
+  - id: webhook
+    plugins:
+      - id: hook
+        uses: webhook
+        with:
+          endpoints:
+            /echo: ${{ setvar "webhook-url" . }}
+          do:
+            method: POST
+            headers:
+              X-Echo: ${{ index . "X-Request" }}
+            body: ${{ . }}
+      - uses: event
+        with:
+          sources:
+          - hook
+          inactive_timeout: 5s
+      - uses: http
+        with:
+          timeout: 5s
+    steps:
+      - uses: http
+        with:
+          request:
+            method: POST
+            url: ${{ var "webhook-url" }}
+            headers:
+              X-Request: ping
+            body: |-
+              {"message": "hi"}
+          response:
+            pass:
+              .status: 200
+              .header["X-Echo"]: ping
+              .body.message: hi
+      - uses: event
+        with:
+          match:
+            .path: /echo
+            .method: POST
+          pass:
+            .body.message: hi
+            .headers["X-Request"]: ping
+`
+
+func TestWebhook(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s, err := hookt.New().Run(ctx, []byte(workflow))
+	if err != nil {
+		t.Fatalf("Run()=%+v", err)
+	}
+
+	if res := s.Results(); len(res) != 0 {
+		t.Fatalf("Results()=%+v", res)
+	}
+}
+
+func TestClose(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	p := proto.New(proto.WithPlugins(webhook.New()))
+
+	w, err := p.Parse(context.Background(), []byte(`
+jobs:
+  - plugins:
+      - uses: webhook
+        with:
+          listen: `+addr+`
+          endpoints:
+            /ready: ""
+    steps: []
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer w.Close()
+
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := http.Get("http://" + addr + "/ready"); err != nil {
+		t.Fatalf("Get()=%v", err)
+	}
+
+	if err := w.Jobs[0].Close(context.Background()); err != nil {
+		t.Fatalf("Close()=%v", err)
+	}
+
+	if _, err := http.Get("http://" + addr + "/ready"); err == nil {
+		t.Fatal("Get()=nil, want error after Close")
+	}
+}
+
+func TestRoute(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	p := proto.New(proto.WithPlugins(webhook.New()))
+
+	w, err := p.Parse(context.Background(), []byte(`
+jobs:
+  - plugins:
+      - uses: webhook
+        with:
+          listen: `+addr+`
+          endpoints:
+            /hooks/{id}: ""
+            /GET /x: ""
+          do:
+            method: POST
+    steps: []
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer w.Close()
+
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatalf("Start()=%v", err)
+	}
+
+	cases := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{"POST", "/hooks/%7Bid%7D", http.StatusOK},
+		{"POST", "/hooks/1", http.StatusNotFound},
+		{"POST", "/GET%20/x", http.StatusOK},
+		{"GET", "/hooks/%7Bid%7D", http.StatusMethodNotAllowed},
+	}
+
+	for _, cas := range cases {
+		req, err := http.NewRequest(cas.method, "http://"+addr+cas.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Do(%s %s)=%v", cas.method, cas.path, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != cas.status {
+			t.Errorf("Do(%s %s)=%d, want %d", cas.method, cas.path, resp.StatusCode, cas.status)
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	p := webhook.New().WithProto(proto.New())
+	p.Config = wire.Config{
+		Listen:    addr,
+		Endpoints: protowire.Object{"/echo": []byte(`""`)},
+		Do:        &wire.Handler{Method: protowire.Generic(`POST`)},
+	}
+
+	c := p.Subscribe(ctx)
+
+	if err := p.Init(ctx, nil); err != nil {
+		t.Fatalf("Init()=%v", err)
+	}
+	defer p.Close(context.Background())
+
+	resp, err := http.Get("http://" + addr + "/echo")
+	if err != nil {
+		t.Fatalf("Get()=%v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("Get()=%d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+
+	go http.Post("http://"+addr+"/echo", "text/plain", nil)
+
+	select {
+	case msg := <-c:
+		if got := string(msg.Bytes()); !strings.Contains(got, `"method":"POST"`) {
+			t.Errorf("Subscribe()=%s, want the POST request only", got)
+		}
+	case <-ctx.Done():
+		t.Fatal("Subscribe(): no message published")
+	}
+}
@@ -1,15 +1,31 @@
 package wire // import "hookt.dev/cmd/pkg/plugin/builtin/webhook/wire"
 
 import (
+	"encoding/json"
+
 	"hookt.dev/cmd/pkg/proto/wire"
 )
 
 type Config struct {
+	Listen    string      `json:"listen,omitempty"`
 	Endpoints wire.Object `json:"endpoints"`
 	Do        *Handler    `json:"do"`
 }
 
+func (c Config) GetListen() string {
+	if c.Listen == "" {
+		return "127.0.0.1:0"
+	}
+	return c.Listen
+}
+
+func (c Config) String() string {
+	p, _ := json.Marshal(c)
+	return string(p)
+}
+
 type Handler struct {
+	Status  wire.Generic `json:"status,omitempty"`
 	Method  wire.Generic `json:"method"`
 	Headers wire.Generic `json:"headers"`
 	Body    wire.Generic `json:"body"`
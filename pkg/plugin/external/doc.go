@@ -0,0 +1,51 @@
+// Package external runs plugins out of process.
+//
+// A workflow uses an external plugin either by path, e.g.
+// uses: ./bin/hkt-plugin-foo, or by name, e.g. uses: foo, in which
+// case an executable named hkt-plugin-foo is looked up in PATH.
+// Relative paths are resolved against the working directory.
+//
+// Every job configuring or using the plugin starts its own process
+// when the workflow starts. The host and the plugin then exchange
+// JSON-RPC 2.0 messages over the standard input and output of the
+// process, one JSON object per line. Anything the plugin writes to
+// its standard error is passed through to the host's standard error.
+//
+// The host sends the following requests to the plugin:
+//
+//	initialize  {"version": 1, "name": "foo", "job": {"id": "a",
+//	            "plugins": [{"id": "x", "uses": "foo"}]},
+//	            "config": {...}}
+//	run         {"step": 0, "id": "#step-0", "with": {...}}
+//	stop        {"step": 0}
+//	shutdown    null
+//
+// initialize is sent once, with the plugin's with object as config;
+// it maps onto proto.Initializer. A plugin that does not reply within
+// InitTimeout is killed. run and stop are sent for every
+// step using the plugin and map onto proto.Runner; step is the index
+// of the step among the steps of the job using the plugin, and run
+// requests of different steps may be in flight at the same time.
+// A successful run returns a null result, a failed one an error.
+// shutdown is sent once the steps of the job complete; the plugin
+// replies and exits, it is killed otherwise.
+//
+// Templates in config and with are evaluated by the host before
+// being sent, at initialization and right before each step runs.
+// Evaluated templates are always strings.
+//
+// The plugin sends the notification
+//
+//	publish     {"message": {...}}
+//
+// to publish a message, a JSON object, to the event plugins using it
+// as a source, mapping onto proto.Subscriber. Messages published to
+// the plugin, see proto.Publisher, are sent as notifications
+//
+//	receive     {"message": {...}}
+//
+// Errors use the JSON-RPC error object. The code CodeTimeout reports
+// a step that timed out, any other code a step that failed.
+//
+// Server implements the plugin side of the protocol.
+package external // import "hookt.dev/cmd/pkg/plugin/external"
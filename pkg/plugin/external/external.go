@@ -0,0 +1,421 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"hookt.dev/cmd/pkg/check"
+	"hookt.dev/cmd/pkg/errors"
+	"hookt.dev/cmd/pkg/proto"
+	protowire "hookt.dev/cmd/pkg/proto/wire"
+	"hookt.dev/cmd/pkg/trace"
+
+	"github.com/lmittmann/tint"
+)
+
+// Prefix is prepended to plugin names to find their executable in PATH.
+const Prefix = "hkt-plugin-"
+
+// InitTimeout is how long a plugin is given to answer initialize
+// before it is killed.
+var InitTimeout = 10 * time.Second
+
+// ShutdownTimeout is how long a plugin is given to exit after
+// shutdown before it is killed.
+var ShutdownTimeout = 5 * time.Second
+
+// Resolve finds the executable of the plugin a workflow uses,
+// either by path or by name in PATH. It does not start it.
+func Resolve(uses string) (proto.Interface, error) {
+	path := uses
+
+	if !strings.ContainsRune(uses, '/') && !filepath.IsAbs(uses) {
+		path = Prefix + uses
+	}
+
+	path, err := exec.LookPath(path)
+	if err != nil {
+		return nil, errors.New("not found: %w", err)
+	}
+
+	return New(uses, path), nil
+}
+
+// Plugin is the host side of an external plugin.
+type Plugin struct {
+	name string
+	path string
+
+	p      *proto.P
+	config json.RawMessage
+	steps  int
+
+	cmd  *exec.Cmd
+	conn *conn
+	c    chan proto.Message
+	q    queue
+	sub  atomic.Bool
+	seq  atomic.Int64
+	done chan struct{}
+	once sync.Once
+}
+
+func New(name, path string) *Plugin {
+	return &Plugin{
+		name: name,
+		path: path,
+		c:    make(chan proto.Message),
+		done: make(chan struct{}),
+	}
+}
+
+func (p *Plugin) WithProto(q *proto.P) *Plugin {
+	p.p = q
+	return p
+}
+
+func (p *Plugin) Name() string {
+	return p.name
+}
+
+func (p *Plugin) Description() string {
+	return "External plugin " + p.path
+}
+
+func (p *Plugin) Plugin(_ context.Context, q *proto.P) any {
+	return New(p.name, p.path).WithProto(q)
+}
+
+func (p *Plugin) UnmarshalJSON(q []byte) error {
+	p.config = append(json.RawMessage(nil), q...)
+	return nil
+}
+
+func (p *Plugin) Init(ctx context.Context, job *proto.Job) error {
+	slog.Debug("external: init",
+		"plugin", p.name,
+		"path", p.path,
+	)
+
+	config, err := evaluate(ctx, p.p, p.config)
+	if err != nil {
+		return errors.New("failed to evaluate config: %w", err)
+	}
+
+	p.cmd = exec.Command(p.path)
+	p.cmd.Stderr = os.Stderr
+
+	w, err := p.cmd.StdinPipe()
+	if err != nil {
+		return errors.New("failed to start %q: %w", p.path, err)
+	}
+
+	r, err := p.cmd.StdoutPipe()
+	if err != nil {
+		return errors.New("failed to start %q: %w", p.path, err)
+	}
+
+	if err := p.cmd.Start(); err != nil {
+		return errors.New("failed to start %q: %w", p.path, err)
+	}
+
+	p.conn = newConn(r, w)
+
+	go p.q.pump(p.deliver)
+
+	go func() {
+		err := p.conn.read(p.handle(ctx))
+		if err != nil {
+			slog.Error("external: read",
+				"plugin", p.name,
+				tint.Err(err),
+			)
+		}
+	}()
+
+	params := &InitParams{
+		Version: Version,
+		Name:    p.name,
+		Job:     Job{ID: job.ID},
+		Config:  config,
+	}
+
+	for _, plugin := range job.Plugins {
+		params.Job.Plugins = append(params.Job.Plugins, PluginRef{
+			ID:   plugin.ID,
+			Uses: plugin.Uses,
+		})
+	}
+
+	ictx, cancel := context.WithTimeout(ctx, InitTimeout)
+	defer cancel()
+
+	if err := p.conn.call(ictx, "initialize", params, nil); err != nil {
+		p.kill()
+		return errors.New("failed to initialize %q: %w", p.name, err)
+	}
+
+	return nil
+}
+
+// kill kills the process of a plugin that failed to initialize,
+// leaving nothing for Close to shut down.
+func (p *Plugin) kill() {
+	p.cmd.Process.Kill()
+	p.cmd.Wait()
+	p.cmd = nil
+}
+
+// handle handles the notifications of the plugin.
+func (p *Plugin) handle(ctx context.Context) func(*message) bool {
+	return func(msg *message) bool {
+		switch msg.Method {
+		case "publish":
+			var params MessageParams
+
+			if err := json.Unmarshal(msg.Params, &params); err != nil {
+				slog.Error("external: publish",
+					"plugin", p.name,
+					tint.Err(err),
+				)
+				return true
+			}
+
+			var obj map[string]any
+
+			if err := json.Unmarshal(params.Message, &obj); err != nil || obj == nil {
+				slog.Error("external: publish",
+					"plugin", p.name,
+					tint.Err(errors.New("message is not an object: %s", params.Message)),
+				)
+				return true
+			}
+
+			if !p.sub.Load() {
+				return true
+			}
+
+			var (
+				index = int(p.seq.Add(1) - 1)
+				m     = &protowire.Message{P: params.Message, I: index}
+			)
+
+			p.q.push(trace.With(ctx, "event-seq", strconv.Itoa(index)), m)
+		default:
+			if msg.ID != nil {
+				p.conn.reply(msg.ID, nil, &Error{
+					Code:    CodeMethodNotFound,
+					Message: "method not found: " + msg.Method,
+				})
+			}
+		}
+
+		return true
+	}
+}
+
+// deliver passes a published message to the subscriber,
+// reporting false once the plugin is closed.
+func (p *Plugin) deliver(ctx context.Context, msg *protowire.Message) bool {
+	tr := trace.ContextSchedule(ctx)
+
+	tr.BeforePublish(ctx, msg)
+	select {
+	case p.c <- msg:
+		tr.Publish(ctx, msg)
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+func (p *Plugin) Subscribe(context.Context) <-chan proto.Message {
+	p.sub.Store(true)
+	return p.c
+}
+
+func (p *Plugin) Publish(context.Context) chan<- proto.Message {
+	c := make(chan proto.Message)
+
+	go func() {
+		for {
+			select {
+			case msg := <-c:
+				err := p.conn.notify("receive", &MessageParams{Message: msg.Bytes()})
+				if err != nil {
+					slog.Error("external: receive",
+						"plugin", p.name,
+						tint.Err(err),
+					)
+				}
+			case <-p.done:
+				return
+			}
+		}
+	}()
+
+	return c
+}
+
+// Close asks the plugin to shut down, killing it when it does not
+// exit within ShutdownTimeout.
+func (p *Plugin) Close(ctx context.Context) error {
+	var err error
+
+	p.once.Do(func() {
+		close(p.done)
+		p.q.close()
+
+		if p.cmd == nil || p.cmd.Process == nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, ShutdownTimeout)
+		defer cancel()
+
+		if e := p.conn.call(ctx, "shutdown", nil, nil); e != nil {
+			slog.Warn("external: shutdown",
+				"plugin", p.name,
+				tint.Err(e),
+			)
+		}
+
+		exited := make(chan error, 1)
+
+		go func() {
+			exited <- p.cmd.Wait()
+		}()
+
+		select {
+		case err = <-exited:
+		case <-ctx.Done():
+			p.cmd.Process.Kill()
+			<-exited
+			err = errors.New("plugin %q killed: %w", p.name, ctx.Err())
+		}
+	})
+
+	return err
+}
+
+func (p *Plugin) Step(ctx context.Context) any {
+	s := &Step{
+		p:  p,
+		i:  p.steps,
+		id: trace.Get(ctx, "step"),
+	}
+	p.steps++
+	return s
+}
+
+type Step struct {
+	p    *Plugin
+	i    int
+	id   string
+	with json.RawMessage
+}
+
+func (s *Step) UnmarshalJSON(p []byte) error {
+	s.with = append(json.RawMessage(nil), p...)
+	return nil
+}
+
+func (s *Step) Run(ctx context.Context, _ *check.S) error {
+	with, err := evaluate(ctx, s.p.p, s.with)
+	if err != nil {
+		return errors.New("failed to evaluate step: %w", err)
+	}
+
+	params := &RunParams{
+		Step: s.i,
+		ID:   s.id,
+		With: with,
+	}
+
+	err = s.p.conn.call(ctx, "run", params, nil)
+
+	var e *Error
+	if errors.As(err, &e) && e.Code == CodeTimeout {
+		return errors.New("step has %w: %w", check.ErrTimeout, err)
+	}
+
+	return err
+}
+
+func (s *Step) Stop(ctx context.Context) {
+	if s.p.conn == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), ShutdownTimeout)
+	defer cancel()
+
+	if err := s.p.conn.call(ctx, "stop", &StopParams{Step: s.i}, nil); err != nil {
+		slog.Warn("external: stop",
+			"plugin", s.p.name,
+			"step", s.id,
+			tint.Err(err),
+		)
+	}
+}
+
+// evaluate evaluates every template of the JSON value raw.
+func evaluate(ctx context.Context, p *proto.P, raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+
+	var v any
+
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+
+	v, err := walk(ctx, p, v)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+func walk(ctx context.Context, p *proto.P, v any) (any, error) {
+	var err error
+
+	switch v := v.(type) {
+	case map[string]any:
+		for k, x := range v {
+			if v[k], err = walk(ctx, p, x); err != nil {
+				return nil, err
+			}
+		}
+	case []any:
+		for i, x := range v {
+			if v[i], err = walk(ctx, p, x); err != nil {
+				return nil, err
+			}
+		}
+	case string:
+		if !strings.Contains(v, "${{") {
+			return v, nil
+		}
+
+		q, err := p.EvaluateContext(ctx, v, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return string(q), nil
+	}
+
+	return v, nil
+}
@@ -0,0 +1,222 @@
+package external_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"hookt.dev/cmd/pkg/hookt"
+	"hookt.dev/cmd/pkg/plugin/external"
+)
+
+// TestMain runs the test binary as an external plugin
+// when started by the host.
+func TestMain(m *testing.M) {
+	switch os.Getenv("HKT_PLUGIN_TEST") {
+	case "1":
+		if err := echo(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case "hang":
+		// Never answer, as a plugin stuck before initialize.
+		io.Copy(io.Discard, os.Stdin)
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// echo publishes the text of every step, prefixed by the greeting
+// of its config, and fails steps with a fail message. Steps with
+// items publish them as a list instead.
+func echo() error {
+	var (
+		srv      = external.NewServer(os.Stdin, os.Stdout)
+		greeting string
+	)
+
+	return srv.Serve(context.Background(), external.Handler{
+		Init: func(_ context.Context, params *external.InitParams) error {
+			var config struct {
+				Greeting string `json:"greeting"`
+			}
+
+			if err := json.Unmarshal(params.Config, &config); err != nil {
+				return err
+			}
+
+			greeting = config.Greeting
+
+			return nil
+		},
+		Run: func(ctx context.Context, params *external.RunParams) error {
+			var with struct {
+				Text  string   `json:"text"`
+				Items []string `json:"items"`
+				Fail  string   `json:"fail"`
+				Wait  bool     `json:"wait"`
+			}
+
+			if err := json.Unmarshal(params.With, &with); err != nil {
+				return err
+			}
+
+			switch {
+			case with.Fail != "":
+				return errors.New(with.Fail)
+			case with.Wait:
+				<-ctx.Done()
+				return &external.Error{Code: external.CodeTimeout, Message: "stopped"}
+			}
+
+			if with.Items != nil {
+				return srv.Publish(with.Items)
+			}
+
+			return srv.Publish(map[string]string{
+				"text": greeting + " " + with.Text,
+			})
+		},
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv("HKT_PLUGIN_TEST", "1")
+
+	cases := map[string]struct {
+		steps string
+		err   string
+	}{
+		"publish": {
+			steps: `
+      - uses: event
+        timeout: 5s
+        with:
+          match:
+            .text: hello ${{ var "name" }}
+      - uses: %[1]s
+        defer: 100ms
+        with:
+          text: ${{ var "name" }}
+`,
+		},
+		"non-object": {
+			steps: `
+      - uses: event
+        timeout: 5s
+        with:
+          match:
+            .text: hello again
+      - uses: %[1]s
+        defer: 100ms
+        with:
+          items: [one, two]
+      - uses: %[1]s
+        defer: 300ms
+        with:
+          text: again
+`,
+		},
+		"fail": {
+			steps: `
+      - uses: %[1]s
+        with:
+          fail: boom
+`,
+			err: "boom",
+		},
+		"timeout": {
+			steps: `
+      - uses: %[1]s
+        timeout: 100ms
+        with:
+          wait: true
+`,
+			err: "timed out",
+		},
+	}
+
+	const workflow = `
+jobs:
+  - id: test
+    plugins:
+      - id: echo
+        uses: %[1]s
+        with:
+          greeting: hello
+      - uses: event
+        with:
+          sources:
+          - echo
+    steps:
+      - uses: %[1]s
+        id: setup
+        with:
+          text: ${{ setvar "name" "world" }}
+`
+
+	for name, cas := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			p := []byte(fmt.Sprintf(workflow+cas.steps, os.Args[0]))
+
+			_, err := hookt.New().Run(ctx, p)
+			if cas.err == "" && err != nil {
+				t.Fatalf("Run()=%+v", err)
+			}
+			if cas.err != "" && (err == nil || !strings.Contains(err.Error(), cas.err)) {
+				t.Fatalf("Run()=%v, want error containing %q", err, cas.err)
+			}
+		})
+	}
+}
+
+func TestInitTimeout(t *testing.T) {
+	t.Setenv("HKT_PLUGIN_TEST", "hang")
+
+	defer func(d time.Duration) { external.InitTimeout = d }(external.InitTimeout)
+	external.InitTimeout = 100 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	p := []byte(fmt.Sprintf(`
+jobs:
+  - plugins:
+      - uses: %[1]s
+        with:
+          greeting: hello
+    steps:
+      - uses: %[1]s
+        with:
+          text: world
+`, os.Args[0]))
+
+	_, err := hookt.New().Run(ctx, p)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "failed to initialize") {
+		t.Fatalf("Run()=%v, want initialize timeout", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Run() did not return before the test deadline")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	if _, err := external.Resolve("hkt-test-missing"); err == nil {
+		t.Fatal("Resolve()=nil, want error")
+	}
+
+	if _, err := external.Resolve(os.Args[0]); err != nil {
+		t.Fatalf("Resolve()=%+v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package external
+
+import (
+	"context"
+	"sync"
+
+	protowire "hookt.dev/cmd/pkg/proto/wire"
+)
+
+// queue buffers the messages published by a plugin, so reading
+// responses never blocks on a slow subscriber.
+type queue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  []item
+	closed bool
+}
+
+type item struct {
+	ctx context.Context
+	msg *protowire.Message
+}
+
+func (q *queue) init() {
+	if q.cond == nil {
+		q.cond = sync.NewCond(&q.mu)
+	}
+}
+
+func (q *queue) push(ctx context.Context, msg *protowire.Message) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.init()
+
+	if q.closed {
+		return
+	}
+
+	q.items = append(q.items, item{ctx: ctx, msg: msg})
+	q.cond.Signal()
+}
+
+func (q *queue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.init()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// pump passes queued messages to deliver in order, until the queue
+// is closed or deliver reports false.
+func (q *queue) pump(deliver func(context.Context, *protowire.Message) bool) {
+	for {
+		q.mu.Lock()
+		q.init()
+		for len(q.items) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return
+		}
+		it := q.items[0]
+		q.items = q.items[1:]
+		q.mu.Unlock()
+
+		if !deliver(it.ctx, it.msg) {
+			return
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package external
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"hookt.dev/cmd/pkg/errors"
+)
+
+// Version is the version of the protocol.
+const Version = 1
+
+// Error codes of the protocol, besides the ones defined
+// by JSON-RPC 2.0.
+const (
+	CodeFailed  = 1
+	CodeTimeout = 2
+
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+)
+
+type InitParams struct {
+	Version int             `json:"version"`
+	Name    string          `json:"name"`
+	Job     Job             `json:"job"`
+	Config  json.RawMessage `json:"config,omitempty"`
+}
+
+type Job struct {
+	ID      string      `json:"id"`
+	Plugins []PluginRef `json:"plugins,omitempty"`
+}
+
+// PluginRef is a plugin configured by a job.
+type PluginRef struct {
+	ID   string `json:"id,omitempty"`
+	Uses string `json:"uses"`
+}
+
+type RunParams struct {
+	Step int             `json:"step"`
+	ID   string          `json:"id"`
+	With json.RawMessage `json:"with,omitempty"`
+}
+
+type StopParams struct {
+	Step int `json:"step"`
+}
+
+type MessageParams struct {
+	Message json.RawMessage `json:"message"`
+}
+
+// Error is a JSON-RPC error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message + " (code " + strconv.Itoa(e.Code) + ")"
+}
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// conn is one end of a JSON-RPC 2.0 connection carrying
+// newline delimited messages.
+type conn struct {
+	mu  sync.Mutex
+	w   io.Writer
+	r   *bufio.Reader
+	seq atomic.Int64
+
+	pending sync.Map
+	done    chan struct{}
+	err     error
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{
+		w:    w,
+		r:    bufio.NewReader(r),
+		done: make(chan struct{}),
+	}
+}
+
+func (c *conn) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+
+	p, err := json.Marshal(msg)
+	if err != nil {
+		return errors.New("failed to marshal message: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.w.Write(append(p, '\n')); err != nil {
+		return errors.New("failed to write message: %w", err)
+	}
+
+	return nil
+}
+
+// call sends a request and waits for its response,
+// decoding its result into result unless it is nil.
+func (c *conn) call(ctx context.Context, method string, params, result any) error {
+	p, err := json.Marshal(params)
+	if err != nil {
+		return errors.New("failed to marshal %s params: %w", method, err)
+	}
+
+	var (
+		id = c.seq.Add(1)
+		ch = make(chan *message, 1)
+	)
+
+	c.pending.Store(id, ch)
+	defer c.pending.Delete(id)
+
+	if err := c.write(&message{ID: &id, Method: method, Params: p}); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.done:
+		return errors.New("connection closed: %w", c.err)
+	case msg := <-ch:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result == nil || len(msg.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(msg.Result, result)
+	}
+}
+
+func (c *conn) notify(method string, params any) error {
+	p, err := json.Marshal(params)
+	if err != nil {
+		return errors.New("failed to marshal %s params: %w", method, err)
+	}
+
+	return c.write(&message{Method: method, Params: p})
+}
+
+func (c *conn) reply(id *int64, result any, err error) error {
+	msg := &message{ID: id}
+
+	if err != nil {
+		var e *Error
+		if !errors.As(err, &e) {
+			e = &Error{Code: CodeFailed, Message: err.Error()}
+		}
+		msg.Error = e
+	} else {
+		p, err := json.Marshal(result)
+		if err != nil {
+			return errors.New("failed to marshal result: %w", err)
+		}
+		msg.Result = p
+	}
+
+	return c.write(msg)
+}
+
+// read reads messages until the connection is closed or handle
+// reports false, passing responses to their calls and everything
+// else to handle.
+func (c *conn) read(handle func(*message) bool) error {
+	defer close(c.done)
+
+	for {
+		p, err := c.r.ReadBytes('\n')
+		if p = bytes.TrimSpace(p); len(p) != 0 {
+			var msg message
+
+			if err := json.Unmarshal(p, &msg); err != nil {
+				c.err = errors.New("failed to read message: %w", err)
+				return c.err
+			}
+
+			if msg.Method == "" && msg.ID != nil {
+				if ch, ok := c.pending.Load(*msg.ID); ok {
+					ch.(chan *message) <- &msg
+				}
+			} else if !handle(&msg) {
+				c.err = io.EOF
+				return nil
+			}
+		}
+		if err == io.EOF {
+			c.err = io.EOF
+			return nil
+		}
+		if err != nil {
+			c.err = err
+			return err
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"hookt.dev/cmd/pkg/errors"
+)
+
+// Handler implements an external plugin. Nil funcs are no-ops.
+type Handler struct {
+	// Init is called with the initialize request.
+	Init func(ctx context.Context, params *InitParams) error
+
+	// Run is called with every run request, concurrently. Its
+	// context is canceled once the step is stopped or the plugin
+	// shuts down.
+	Run func(ctx context.Context, params *RunParams) error
+
+	// Receive is called with every message published to the plugin.
+	Receive func(ctx context.Context, msg json.RawMessage)
+}
+
+// Server is the plugin side of the protocol.
+type Server struct {
+	conn *conn
+
+	mu   sync.Mutex
+	runs map[int]context.CancelFunc
+}
+
+// NewServer returns a server reading requests from r and
+// writing responses to w, usually os.Stdin and os.Stdout.
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		conn: newConn(r, w),
+		runs: make(map[int]context.CancelFunc),
+	}
+}
+
+// Publish publishes msg, marshaled to a JSON object, to the event
+// plugins using the plugin as a source.
+func (s *Server) Publish(msg any) error {
+	p, err := json.Marshal(msg)
+	if err != nil {
+		return errors.New("failed to marshal message: %w", err)
+	}
+
+	return s.conn.notify("publish", &MessageParams{Message: p})
+}
+
+// Serve handles requests until shutdown or until the host
+// closes the connection.
+func (s *Server) Serve(ctx context.Context, h Handler) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		shutdown *int64
+	)
+
+	err := s.conn.read(func(msg *message) bool {
+		switch msg.Method {
+		case "initialize":
+			var params InitParams
+
+			if err := s.decode(msg, &params); err != nil {
+				return true
+			}
+
+			if params.Version != Version {
+				s.conn.reply(msg.ID, nil, &Error{
+					Code:    CodeInvalidParams,
+					Message: "unsupported protocol version",
+				})
+				return true
+			}
+
+			var err error
+			if h.Init != nil {
+				err = h.Init(ctx, &params)
+			}
+
+			s.conn.reply(msg.ID, nil, err)
+		case "run":
+			var params RunParams
+
+			if err := s.decode(msg, &params); err != nil {
+				return true
+			}
+
+			ctx, cancel := context.WithCancel(ctx)
+
+			s.mu.Lock()
+			s.runs[params.Step] = cancel
+			s.mu.Unlock()
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer cancel()
+
+				var err error
+				if h.Run != nil {
+					err = h.Run(ctx, &params)
+				}
+
+				s.conn.reply(msg.ID, nil, err)
+			}()
+		case "stop":
+			var params StopParams
+
+			if err := s.decode(msg, &params); err != nil {
+				return true
+			}
+
+			s.mu.Lock()
+			if cancel, ok := s.runs[params.Step]; ok {
+				cancel()
+				delete(s.runs, params.Step)
+			}
+			s.mu.Unlock()
+
+			s.conn.reply(msg.ID, nil, nil)
+		case "receive":
+			var params MessageParams
+
+			if err := json.Unmarshal(msg.Params, &params); err != nil || h.Receive == nil {
+				return true
+			}
+
+			h.Receive(ctx, params.Message)
+		case "shutdown":
+			shutdown = msg.ID
+			return false
+		default:
+			if msg.ID != nil {
+				s.conn.reply(msg.ID, nil, &Error{
+					Code:    CodeMethodNotFound,
+					Message: "method not found: " + msg.Method,
+				})
+			}
+		}
+
+		return true
+	})
+
+	cancel()
+	wg.Wait()
+
+	if shutdown != nil {
+		return s.conn.reply(shutdown, nil, nil)
+	}
+
+	return err
+}
+
+func (s *Server) decode(msg *message, v any) error {
+	if err := json.Unmarshal(msg.Params, v); err != nil {
+		s.conn.reply(msg.ID, nil, &Error{
+			Code:    CodeInvalidParams,
+			Message: err.Error(),
+		})
+		return err
+	}
+
+	return nil
+}
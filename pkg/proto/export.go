@@ -0,0 +1,65 @@
+package proto
+
+import (
+	"context"
+	"sort"
+
+	"hookt.dev/cmd/pkg/errors"
+
+	"github.com/itchyny/gojq"
+)
+
+// Export stores the first result of every jq query of exports
+// against obj into the variable it maps to, which later steps
+// and jobs read with the var template function.
+func (p *P) Export(ctx context.Context, exports map[string]string, obj any) error {
+	var err error
+
+	for _, k := range keys(exports) {
+		q, e := gojq.Parse(k)
+		if e != nil {
+			err = errors.Join(err, errors.New("failed to parse jq %q: %w", k, e))
+			continue
+		}
+
+		v, ok := q.RunWithContext(ctx, obj).Next()
+		if !ok {
+			err = errors.Join(err, errors.New("failed to export jq %q: no result", k))
+			continue
+		}
+		if e, ok := v.(error); ok {
+			err = errors.Join(err, errors.New("failed to export jq %q: %w", k, e))
+			continue
+		}
+
+		p.t.Vars.Store(exports[k], v)
+	}
+
+	return err
+}
+
+// CheckExports compiles the jq queries of exports
+// and checks every one maps to a variable name.
+func (p *P) CheckExports(exports map[string]string) error {
+	var err error
+
+	for _, k := range keys(exports) {
+		if _, e := gojq.Parse(k); e != nil {
+			err = errors.Join(err, errors.New("failed to parse jq %q: %w", k, e))
+		}
+		if exports[k] == "" {
+			err = errors.Join(err, errors.New("jq %q is exported to an empty variable name", k))
+		}
+	}
+
+	return err
+}
+
+func keys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
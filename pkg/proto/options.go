@@ -1,5 +1,7 @@
 package proto
 
+import "time"
+
 func WithPlugins(plugins ...Interface) func(*P) {
 	return func(p *P) {
 		for _, plugin := range plugins {
@@ -8,8 +10,49 @@ func WithPlugins(plugins ...Interface) func(*P) {
 	}
 }
 
+// WithRegistry registers the plugins of r, replacing plugins
+// registered with the same name.
+func WithRegistry(r Registry) func(*P) {
+	return func(p *P) {
+		for name, plugin := range r {
+			p.m[name] = plugin
+		}
+	}
+}
+
 func WithTOptions(opts ...TOption) func(*P) {
 	return func(p *P) {
 		p.t.Options = append(p.t.Options, opts...)
 	}
 }
+
+// WithVarTimeout sets how long the var and step template functions
+// wait for a variable to be set or a step to end when the context
+// has no deadline; zero waits until the context is done.
+func WithVarTimeout(d time.Duration) func(*P) {
+	return func(p *P) {
+		p.t.VarTimeout = d
+	}
+}
+
+// WithResolver sets the function used to find the plugins a workflow
+// uses that are not registered by name, e.g. external plugins.
+func WithResolver(resolve func(uses string) (Interface, error)) func(*P) {
+	return func(p *P) {
+		p.resolve = resolve
+	}
+}
+
+// WithVars sets variables readable with the var template
+// function in every workflow, as if set by setvar. They take
+// precedence over the vars and inputs of the workflow.
+func WithVars(vars map[string]any) func(*P) {
+	return func(p *P) {
+		if p.overrides == nil {
+			p.overrides = make(map[string]any, len(vars))
+		}
+		for name, value := range vars {
+			p.overrides[name] = value
+		}
+	}
+}
@@ -18,6 +18,12 @@ import (
 type Pattern struct {
 	Key   *gojq.Query
 	Match func(context.Context, any) (bool, error)
+
+	// Quantifier is one of "any", "all" or "count", set by the $any,
+	// $all and $count markers, and controls how the results of Key
+	// are matched; when empty only the first result is matched.
+	Quantifier string
+	Want       any
 }
 
 type Patterns []*Pattern
@@ -26,28 +32,88 @@ func (p Patterns) Match(ctx context.Context, obj any) (bool, error) {
 	for _, p := range p {
 		ctx := pattern(ctx, p.Key.String())
 
-		it := p.Key.RunWithContext(ctx, obj)
-
 		slog.Debug("pattern",
 			"query", p.Key.String(),
+			"quantifier", p.Quantifier,
 		)
 
-		// TODO: Handle multiple results?
+		ok, err := p.match(ctx, obj)
+		if err != nil {
+			return false, errors.New("failed to match jq %q: %w", p.Key.String(), err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (p *Pattern) match(ctx context.Context, obj any) (bool, error) {
+	var (
+		tr     = trace.ContextPattern(ctx)
+		it     = p.Key.RunWithContext(ctx, obj)
+		values []any
+	)
+
+	for {
 		v, ok := it.Next()
 		if !ok {
+			break
+		}
+
+		if err, ok := v.(error); ok {
+			if err, ok := err.(*gojq.HaltError); ok && err.Value() == nil {
+				break
+			}
+
+			tr.QueryError(ctx, obj, err)
 			return false, nil
 		}
 
+		values = append(values, v)
+
+		if p.Quantifier == "" {
+			break
+		}
+	}
+
+	switch p.Quantifier {
+	case "":
+		if len(values) == 0 {
+			return false, nil
+		}
+		return p.Match(ctx, values[0])
+	case "count":
+		ok := cmpEqual(p.Want, len(values))
+		tr.EqualMatch(ctx, map[string]any{"$count": p.Want}, len(values), ok)
+		return ok, nil
+	}
+
+	var n int
+
+	for _, v := range values {
 		ok, err := p.Match(ctx, v)
 		if err != nil {
-			return false, errors.New("failed to match jq %q: %w", p.Key.String(), err)
+			return false, err
 		}
-		if !ok {
-			return false, nil
+		if ok {
+			n++
 		}
 	}
 
-	return true, nil
+	var ok bool
+
+	switch p.Quantifier {
+	case "any":
+		ok = n > 0
+	case "all":
+		ok = n > 0 && n == len(values)
+	}
+
+	tr.EqualMatch(ctx, map[string]any{"$" + p.Quantifier: p.Want}, values, ok)
+
+	return ok, nil
 }
 
 func pattern(ctx context.Context, name string) context.Context {
@@ -86,7 +152,7 @@ loop:
 			continue loop
 		}
 
-		p, e := t.Evaluate(s, nil)
+		p, e := t.EvaluateContext(ctx, s, nil)
 		if e != nil {
 			err = errors.Join(
 				err,
@@ -151,15 +217,42 @@ func (p *P) Patterns(ctx context.Context, obj wire.Object, opts ...TOption) (Pat
 			continue
 		}
 
+		q.Quantifier, want, e = quantifier(want)
+		if e != nil {
+			err = errors.Join(
+				err,
+				errors.New("failed to parse value for jq %q: %w", k, e),
+			)
+			continue
+		}
+
+		q.Want = want
+
 		slog.Debug("building pattern",
 			"key", k,
+			"quantifier", q.Quantifier,
 			"pattern", want,
 		)
 
+		if q.Quantifier == "count" {
+			pt = append(pt, &q)
+			continue
+		}
+
+		if exists, ok := presence(want); ok {
+			q.Match = func(_ context.Context, got any) (bool, error) {
+				ok := exists == (got != nil)
+				tr.EqualMatch(ctx, want, got, ok)
+				return ok, nil
+			}
+			pt = append(pt, &q)
+			continue
+		}
+
 		switch want := want.(type) {
 		case bool:
 			q.Match = func(_ context.Context, got any) (bool, error) {
-				ok := want && len(raw) != 0
+				ok := got == want
 				tr.EqualMatch(ctx, want, got, ok)
 				return ok, nil
 			}
@@ -183,6 +276,77 @@ func (p *P) Patterns(ctx context.Context, obj wire.Object, opts ...TOption) (Pat
 	return pt, err
 }
 
+// CheckPatterns compiles the jq keys of obj and reads their values
+// without evaluating them, reporting every invalid pattern.
+func (p *P) CheckPatterns(obj wire.Object) error {
+	var (
+		err  error
+		keys = make([]string, 0, len(obj))
+	)
+
+	for k := range obj {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if _, e := gojq.Parse(k); e != nil {
+			err = errors.Join(err, errors.New("failed to parse jq %q: %w", k, e))
+			continue
+		}
+
+		var want any
+
+		if e := yaml.Unmarshal(obj[k], &want); e != nil {
+			err = errors.Join(err, errors.New("failed to parse value for jq %q: %w", k, e))
+			continue
+		}
+
+		if _, _, e := quantifier(want); e != nil {
+			err = errors.Join(err, errors.New("failed to parse value for jq %q: %w", k, e))
+		}
+	}
+
+	return err
+}
+
+// quantifier unwraps values of the form {$all: want}, {$any: want}
+// and {$count: n}, returning the quantifier and the wrapped value.
+// The markers are prefixed with $ so that literal objects with an
+// all, any or count key are still compared by equality.
+func quantifier(v any) (string, any, error) {
+	m, ok := v.(map[string]any)
+	if !ok || len(m) != 1 {
+		return "", v, nil
+	}
+
+	for k, want := range m {
+		switch k {
+		case "$all", "$any":
+			return k[1:], want, nil
+		case "$count":
+			if _, ok := want.(float64); !ok {
+				return "", nil, errors.New("$count must be a number, got %T", want)
+			}
+			return k[1:], want, nil
+		}
+	}
+
+	return "", v, nil
+}
+
+// presence reports whether want is a {$exists: bool} pattern, which
+// matches on whether the key is set instead of comparing its value.
+func presence(want any) (exists, ok bool) {
+	m, ok := want.(map[string]any)
+	if !ok || len(m) != 1 {
+		return false, false
+	}
+	exists, ok = m["$exists"].(bool)
+	return exists, ok
+}
+
 func cmpEqual(want, got any) bool {
 	if fmt.Sprint(want) == fmt.Sprint(got) {
 		return true
@@ -2,9 +2,13 @@ package proto_test
 
 import (
 	"context"
+	"errors"
 	"strconv"
+	"strings"
 	"testing"
 
+	"hookt.dev/cmd/pkg/check"
+	"hookt.dev/cmd/pkg/proto"
 	"hookt.dev/cmd/pkg/proto/wire"
 	"hookt.dev/cmd/pkg/trace"
 )
@@ -39,7 +43,7 @@ func TestPattern(t *testing.T) {
 		},
 		2: {
 			wire.Object{
-				".foo.three": []byte(`true`),
+				".foo.three": []byte(`{$exists: true}`),
 			},
 			map[string]any{
 				"foo": map[string]any{
@@ -52,7 +56,7 @@ func TestPattern(t *testing.T) {
 			wire.Object{
 				".foo.one":   []byte(`"bar"`),
 				".foo.two":   []byte(`"10"`),
-				".foo.three": []byte(`true`),
+				".foo.three": []byte(`{$exists: true}`),
 			},
 			map[string]any{
 				"foo": map[string]any{
@@ -98,7 +102,7 @@ func TestPattern(t *testing.T) {
 		},
 		7: {
 			wire.Object{
-				".foo.two": []byte(`false`),
+				".foo.two": []byte(`{$exists: false}`),
 			},
 			map[string]any{
 				"foo": map[string]any{
@@ -134,6 +138,140 @@ func TestPattern(t *testing.T) {
 			},
 			false,
 		},
+		10: {
+			wire.Object{
+				".foo.two": []byte(`true`),
+			},
+			map[string]any{
+				"foo": map[string]any{
+					"one": "rab",
+				},
+			},
+			false,
+		},
+		11: {
+			wire.Object{
+				".enabled": []byte(`false`),
+			},
+			map[string]any{
+				"enabled": false,
+			},
+			true,
+		},
+		12: {
+			wire.Object{
+				".ok": []byte(`true`),
+			},
+			map[string]any{
+				"ok": false,
+			},
+			false,
+		},
+		13: {
+			wire.Object{
+				".foo.one": []byte(`{$exists: true}`),
+			},
+			map[string]any{
+				"foo": map[string]any{
+					"two": "10",
+				},
+			},
+			false,
+		},
+		14: {
+			wire.Object{
+				".items[].status": []byte(`{$all: "ok"}`),
+			},
+			map[string]any{
+				"items": []any{
+					map[string]any{"status": "ok"},
+					map[string]any{"status": "ok"},
+				},
+			},
+			true,
+		},
+		15: {
+			wire.Object{
+				".items[].status": []byte(`{$all: "ok"}`),
+			},
+			map[string]any{
+				"items": []any{
+					map[string]any{"status": "ok"},
+					map[string]any{"status": "failed"},
+				},
+			},
+			false,
+		},
+		16: {
+			wire.Object{
+				".items[].status": []byte(`{$any: "failed"}`),
+			},
+			map[string]any{
+				"items": []any{
+					map[string]any{"status": "ok"},
+					map[string]any{"status": "failed"},
+				},
+			},
+			true,
+		},
+		17: {
+			wire.Object{
+				".items[].status": []byte(`{$any: "failed"}`),
+			},
+			map[string]any{
+				"items": []any{},
+			},
+			false,
+		},
+		18: {
+			wire.Object{
+				".items[]": []byte(`{$count: 2}`),
+			},
+			map[string]any{
+				"items": []any{1.0, 2.0},
+			},
+			true,
+		},
+		19: {
+			wire.Object{
+				".items[]": []byte(`{$count: 3}`),
+			},
+			map[string]any{
+				"items": []any{1.0, 2.0},
+			},
+			false,
+		},
+		20: {
+			wire.Object{
+				".foo": []byte(`{one: bar}`),
+			},
+			map[string]any{
+				"foo": map[string]any{
+					"one": "bar",
+				},
+			},
+			true,
+		},
+		21: {
+			wire.Object{
+				".filter": []byte(`{all: true}`),
+				".meta":   []byte(`{count: 3}`),
+			},
+			map[string]any{
+				"filter": map[string]any{"all": true},
+				"meta":   map[string]any{"count": 3.0},
+			},
+			true,
+		},
+		22: {
+			wire.Object{
+				".meta": []byte(`{count: 3}`),
+			},
+			map[string]any{
+				"meta": []any{1.0, 2.0, 3.0},
+			},
+			false,
+		},
 	}
 
 	p := newP()
@@ -142,7 +280,7 @@ func TestPattern(t *testing.T) {
 
 	for _, cas := range cases {
 		t.Run("", func(t *testing.T) {
-			pt, err := p.Pattern(ctx, cas.raw)
+			pt, err := p.Patterns(ctx, cas.raw)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -158,3 +296,77 @@ func TestPattern(t *testing.T) {
 		})
 	}
 }
+
+func TestPatternSetError(t *testing.T) {
+	var (
+		p   = newP()
+		s   check.S
+		ctx = context.Background()
+	)
+
+	ctx = trace.WithPattern(ctx, trace.LogPattern().Join(s.Trace()))
+	ctx = trace.With(ctx, "pattern-group", "pass")
+
+	raw := wire.Object{
+		".status": []byte(strconv.Quote(`${{ if ne . 200.0 }}${{ seterror (printf "unexpected status %v" .) }}${{ end }}true`)),
+	}
+
+	pt, err := p.Patterns(ctx, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := pt.Match(ctx, map[string]any{"status": 200.0})
+	if err != nil || !ok {
+		t.Fatalf("match: Match()=%t, %+v", ok, err)
+	}
+
+	ok, err = pt.Match(ctx, map[string]any{"status": 500.0})
+	if ok {
+		t.Fatal("match: got true, want false")
+	}
+
+	var f *proto.Failure
+	if !errors.As(err, &f) || f.Reason != "unexpected status 500" {
+		t.Fatalf("match: Match()=%+v, want seterror failure", err)
+	}
+
+	got := s.Records[0].Failures()
+	if len(got) != 1 || got[0].Key != ".status" || got[0].Error != f.Reason {
+		t.Errorf("Failures()=%+v", got)
+	}
+}
+
+func TestPatternQueryError(t *testing.T) {
+	var (
+		p   = newP()
+		s   check.S
+		ctx = context.Background()
+	)
+
+	ctx = trace.WithPattern(ctx, trace.LogPattern().Join(s.Trace()))
+	ctx = trace.With(ctx, "pattern-group", "match")
+
+	raw := wire.Object{
+		".items[].status": []byte(`{$all: ok}`),
+	}
+
+	pt, err := p.Patterns(ctx, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := pt.Match(ctx, map[string]any{"items": "none"})
+	if err != nil || ok {
+		t.Fatalf("match: Match()=%t, %+v", ok, err)
+	}
+
+	got := s.Records[0].Failures()
+	if len(got) != 1 || got[0].Key != ".items[].status" || !strings.Contains(got[0].Error, "cannot iterate") {
+		t.Errorf("Failures()=%+v", got)
+	}
+
+	if _, err := p.Patterns(ctx, wire.Object{".items[]": []byte(`{$count: many}`)}); err == nil {
+		t.Error("Patterns()=nil, want count error")
+	}
+}
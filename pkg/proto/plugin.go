@@ -29,7 +29,39 @@ type Initializer interface {
 	Init(context.Context, *Job) error
 }
 
+// Validator is implemented by plugins and steps that check their
+// configuration against the job without starting anything.
+type Validator interface {
+	Validate(context.Context, *Job) error
+}
+
+// Closer is implemented by plugins that release resources, such as
+// goroutines, connections or listeners, once their job completes.
+type Closer interface {
+	Close(context.Context) error
+}
+
 type Runner interface {
 	Run(context.Context, *check.S) error
 	Stop(ctx context.Context)
 }
+
+// Describer is implemented by plugins that describe the wire types
+// of their configuration and steps, from which the schema of the
+// with objects is built.
+type Describer interface {
+	Describe() (config, step any)
+}
+
+// Documenter is implemented by plugins with a one-line description,
+// shown when listing the registered plugins.
+type Documenter interface {
+	Description() string
+}
+
+// Outputter is implemented by steps exposing outputs once they
+// passed, e.g. a response or a matched message, which later steps
+// of the job read with the step template function.
+type Outputter interface {
+	Outputs() any
+}
@@ -4,11 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"log/slog"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"hookt.dev/cmd/pkg/async"
 	"hookt.dev/cmd/pkg/errors"
 	"hookt.dev/cmd/pkg/proto/wire"
+	"hookt.dev/cmd/pkg/schema"
 	"hookt.dev/cmd/pkg/trace"
 
 	"sigs.k8s.io/yaml"
@@ -16,22 +22,30 @@ import (
 
 type Workflow struct {
 	Jobs []Job
+
+	cancel context.CancelFunc
 }
 
 type Job struct {
 	ID      string
+	Needs   []string
 	Plugins []Plugin
 	Steps   []Step
+
+	t     *T
+	close *sync.Once
 }
 
 type Condition struct{}
 
 type Step struct {
-	Uses  string
-	ID    string
-	Desc  string
-	With  any
-	Ready context.Context // defer + timeout + wait_for
+	Uses    string
+	ID      string
+	Desc    string
+	With    any
+	Defer   time.Duration
+	Timeout time.Duration
+	WaitFor string
 }
 
 type Plugin struct {
@@ -41,14 +55,17 @@ type Plugin struct {
 }
 
 type P struct {
-	t *T
-	m map[string]Interface
+	t         *T
+	m         Registry
+	overrides map[string]any
+
+	resolve func(string) (Interface, error)
 }
 
 func New(opts ...func(*P)) *P {
 	p := &P{
 		t: NewT(),
-		m: make(map[string]Interface),
+		m: make(Registry),
 	}
 	return p.With(opts...)
 }
@@ -60,17 +77,351 @@ func (p *P) With(opts ...func(*P)) *P {
 	return p
 }
 
+// fork returns a copy of p with its own template variables,
+// so that workflows parsed concurrently do not share state.
+func (p *P) fork() *P {
+	q := &P{
+		t: &T{
+			Options:    slices.Clone(p.t.Options),
+			Vars:       &async.Map{},
+			Steps:      &async.Map{},
+			Jobs:       &async.Map{},
+			VarTimeout: p.t.VarTimeout,
+		},
+		m:         maps.Clone(p.m),
+		overrides: p.overrides,
+		resolve:   p.resolve,
+	}
+
+	for name, value := range p.overrides {
+		q.t.Vars.Store(name, value)
+	}
+
+	return q
+}
+
+// lookup returns the plugin registered as uses, resolving
+// and registering it when it is not known yet.
+func (p *P) lookup(uses string) (Interface, error) {
+	if iface, ok := p.m[uses]; ok {
+		return iface, nil
+	}
+
+	if p.resolve == nil {
+		return nil, errors.New("not found")
+	}
+
+	iface, err := p.resolve(uses)
+	if err != nil {
+		return nil, err
+	}
+
+	p.m[uses] = iface
+
+	return iface, nil
+}
+
+// Plugins lists the registered plugins.
+func (p *P) Plugins() []Info {
+	return p.m.Plugins()
+}
+
+// Schema returns the JSON Schema of workflows, with the with
+// objects of plugins implementing Describer constrained by
+// their wire types.
+func (p *P) Schema() *schema.Schema {
+	var plugins []schema.Plugin
+
+	for name, iface := range p.m {
+		d, ok := iface.(Describer)
+		if !ok {
+			continue
+		}
+
+		config, step := d.Describe()
+
+		plugins = append(plugins, schema.Plugin{
+			Name:   name,
+			Config: config,
+			Step:   step,
+		})
+	}
+
+	slices.SortFunc(plugins, func(a, b schema.Plugin) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return schema.Workflow(plugins...)
+}
+
+// Parse wires the workflow without starting anything; plugins
+// are initialized by Workflow.Start.
 func (p *P) Parse(ctx context.Context, q []byte) (*Workflow, error) {
+	raw, err := p.read(q)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := p.parse(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Start initializes the plugins of every job. The plugins run until
+// ctx is done or Close is called, which must be done even when Start
+// fails.
+func (w *Workflow) Start(ctx context.Context) error {
+	ctx, w.cancel = context.WithCancel(ctx)
+
+	for i := range w.Jobs {
+		j := &w.Jobs[i]
+
+		ctx := trace.With(ctx, "job", j.ID)
+
+		for k := range j.Plugins {
+			p := &j.Plugins[k]
+
+			slog.Debug("initializing plugins",
+				"index", k,
+				"plugin", p.Uses,
+			)
+
+			init, ok := p.With.(Initializer)
+			if !ok {
+				return errors.New("%s: error initializing plugin %q: does not implement proto.Initializer", j.ID, p.Uses)
+			}
+
+			if err := init.Init(ctx, j); err != nil {
+				return errors.New("%s: error initializing plugin %q: %w", j.ID, p.Uses, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Close closes the jobs that are not closed yet
+// and stops the plugins started by Start.
+func (w *Workflow) Close() error {
+	var err error
+
+	for i := range w.Jobs {
+		err = errors.Join(err, w.Jobs[i].Close(context.Background()))
+	}
+
+	if w.cancel != nil {
+		w.cancel()
+	}
+
+	return err
+}
+
+// Finish records the end of a step, making its outputs readable
+// with the step template function when it passed, or err otherwise.
+func (j *Job) Finish(s *Step, err error) {
+	if j.t == nil {
+		return
+	}
+
+	res := &stepResult{err: err}
+
+	if o, ok := s.With.(Outputter); ok && err == nil {
+		res.outputs = o.Outputs()
+	}
+
+	j.t.Steps.Store(stepKey{job: j.ID, step: s.ID}, res)
+}
+
+// Close closes every plugin of the job implementing Closer.
+// Closing a parsed job more than once is a no-op.
+func (j *Job) Close(ctx context.Context) (err error) {
+	if j.close == nil {
+		return j.closePlugins(ctx)
+	}
+
+	j.close.Do(func() {
+		err = j.closePlugins(ctx)
+	})
+
+	return err
+}
+
+func (j *Job) closePlugins(ctx context.Context) error {
+	var err error
+
+	for _, p := range j.Plugins {
+		c, ok := p.With.(Closer)
+		if !ok {
+			continue
+		}
+
+		slog.Debug("closing plugins",
+			"job", j.ID,
+			"plugin", p.Uses,
+		)
+
+		if e := c.Close(ctx); e != nil {
+			err = errors.Join(err, errors.New("%s: error closing plugin %q: %w", j.ID, p.Uses, e))
+		}
+	}
+
+	return err
+}
+
+// Validate checks the workflow without initializing any plugin,
+// reporting every problem found: schema violations, unknown plugins,
+// invalid ids, durations and references, jq keys and templates that
+// do not compile and the errors of plugins and steps implementing
+// Validator.
+func (p *P) Validate(ctx context.Context, q []byte) error {
+	var err error
+
+	if e := p.Schema().Validate(q); e != nil {
+		err = errors.New("error validating workflow: %w", e)
+	}
+
+	raw, e := wire.XParse(q)
+	if e != nil {
+		return errors.Join(err, errors.New("error parsing workflow: %w", e))
+	}
+
+	err = errors.Join(err, p.templates(raw))
+
+	w, e := p.parse(ctx, raw)
+	err = errors.Join(err, e)
+
+	for i := range w.Jobs {
+		j := &w.Jobs[i]
+
+		ctx := trace.With(ctx, "job", j.ID)
+
+		for _, plugin := range j.Plugins {
+			v, ok := plugin.With.(Validator)
+			if !ok {
+				continue
+			}
+
+			e := v.Validate(ctx, j)
+			err = errors.Join(err, prefix(e, "%s: error validating plugin %q", j.ID, plugin.Uses))
+		}
+
+		for _, step := range j.Steps {
+			v, ok := step.With.(Validator)
+			if !ok {
+				continue
+			}
+
+			ctx := trace.With(ctx, "step", step.ID)
+
+			e := v.Validate(ctx, j)
+			err = errors.Join(err, prefix(e, "%s/%s: error validating plugin %q step", j.ID, step.ID, step.Uses))
+		}
+	}
+
+	return err
+}
+
+// templates compiles every template found in the with
+// objects of the workflow plugins and steps.
+func (p *P) templates(raw *wire.Workflow) error {
+	var err error
+
+	for i, job := range raw.Jobs {
+		for k, plugin := range job.Plugins {
+			path := "jobs[" + strconv.Itoa(i) + "].plugins[" + strconv.Itoa(k) + "].with"
+			err = errors.Join(err, p.compile(path, plugin.With))
+		}
+
+		for k, step := range job.Steps {
+			path := "jobs[" + strconv.Itoa(i) + "].steps[" + strconv.Itoa(k) + "].with"
+			err = errors.Join(err, p.compile(path, step.With))
+		}
+	}
+
+	return err
+}
+
+func (p *P) compile(path string, raw json.RawMessage) error {
+	var (
+		v    any
+		err  error
+		walk func(path string, v any)
+	)
+
+	if e := yaml.Unmarshal(raw, &v); e != nil {
+		return errors.New("%s: %w", path, e)
+	}
+
+	walk = func(path string, v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+
+			slices.Sort(keys)
+
+			for _, k := range keys {
+				walk(schema.Join(path, k), v[k])
+			}
+		case []any:
+			for i, v := range v {
+				walk(path+"["+strconv.Itoa(i)+"]", v)
+			}
+		case string:
+			if !strings.Contains(v, "${{") {
+				return
+			}
+
+			if _, e := p.t.Parse("", v); e != nil {
+				err = errors.Join(err, errors.New("%s: failed to parse template: %w", path, e))
+			}
+		}
+	}
+
+	walk(path, v)
+
+	return err
+}
+
+// read validates the workflow against the schema and decodes it.
+func (p *P) read(q []byte) (*wire.Workflow, error) {
+	if err := p.Schema().Validate(q); err != nil {
+		return nil, errors.New("error validating workflow: %w", err)
+	}
+
 	raw, err := wire.XParse(q)
 	if err != nil {
 		return nil, errors.New("error parsing workflow: %w", err)
 	}
 
+	return raw, nil
+}
+
+// parse wires the workflow without initializing its plugins,
+// reporting every wiring error at once; the workflow is returned
+// along with the errors, leaving the invalid parts unset.
+func (p *P) parse(ctx context.Context, raw *wire.Workflow) (*Workflow, error) {
+	p = p.fork()
+
 	var (
-		w  Workflow
-		tr = trace.ContextJob(ctx)
+		w   Workflow
+		err error
+		tr  = trace.ContextJob(ctx)
 	)
 
+	if e := p.vars(raw); e != nil {
+		err = errors.Join(err, errors.New("error reading inputs: %w", e))
+	}
+
+	if e := needs(raw.Jobs); e != nil {
+		err = errors.Join(err, errors.New("error reading jobs: %w", e))
+	}
+
 	w.Jobs = make([]Job, len(raw.Jobs))
 
 	uniq := make(map[string]struct{})
@@ -79,7 +430,8 @@ func (p *P) Parse(ctx context.Context, q []byte) (*Workflow, error) {
 		j := &w.Jobs[i]
 
 		if strings.HasPrefix(job.ID, "#") {
-			return nil, errors.New("#job-%d: error reading job: id cannot start with #", i)
+			err = errors.Join(err, errors.New("#job-%d: error reading job: id cannot start with #", i))
+			continue
 		}
 
 		slog.Debug("wiring jobs",
@@ -89,11 +441,15 @@ func (p *P) Parse(ctx context.Context, q []byte) (*Workflow, error) {
 
 		tr.WireJob(i, &job)
 
-		j.ID = nonempty(job.ID, "#job-"+strconv.Itoa(i))
+		j.ID = jobID(i, &job)
+		j.Needs = job.Needs
+		j.t = p.t
+		j.close = new(sync.Once)
 		j.Plugins = make([]Plugin, len(job.Plugins))
 
 		if _, ok := uniq[j.ID]; ok {
-			return nil, errors.New("#job-%d: error reading job: duplicate id %q", i, j.ID)
+			err = errors.Join(err, errors.New("#job-%d: error reading job: duplicate id %q", i, j.ID))
+			continue
 		}
 
 		uniq[j.ID] = struct{}{}
@@ -101,9 +457,10 @@ func (p *P) Parse(ctx context.Context, q []byte) (*Workflow, error) {
 		ctx := trace.With(ctx, "job", j.ID)
 
 		for k, plugin := range job.Plugins {
-			iface, ok := p.m[plugin.Uses]
-			if !ok {
-				return nil, errors.New("error reading plugin %q config: not found", plugin.Uses)
+			iface, e := p.lookup(plugin.Uses)
+			if e != nil {
+				err = errors.Join(err, errors.New("%s: error reading plugin %q config: %w", j.ID, plugin.Uses, e))
+				continue
 			}
 
 			slog.Debug("wiring plugins",
@@ -117,8 +474,9 @@ func (p *P) Parse(ctx context.Context, q []byte) (*Workflow, error) {
 			q.Uses = plugin.Uses
 			q.With = iface.Plugin(ctx, p)
 
-			if err := json.Unmarshal(plugin.With, q.With); err != nil {
-				return nil, errors.New("error reading plugin %q config: %w", plugin.Uses, err)
+			if e := json.Unmarshal(plugin.With, q.With); e != nil {
+				err = errors.Join(err, errors.New("%s: error reading plugin %q config: %w", j.ID, plugin.Uses, e))
+				continue
 			}
 
 			tr.WirePlugin(k, &plugin, q.With)
@@ -129,32 +487,26 @@ func (p *P) Parse(ctx context.Context, q []byte) (*Workflow, error) {
 		uniq := make(map[string]struct{})
 
 		for k, step := range job.Steps {
-			iface, ok := p.m[step.Uses]
-			if !ok {
-				return nil, errors.New("#step-%d: error reading plugin %q step: not found", k, step.Uses)
-			}
-
-			if strings.HasPrefix(step.ID, "#") {
-				return nil, errors.New("#step-%d: error reading plugin %q step: id cannot start with #", k, step.Uses)
-			}
-
 			s := &j.Steps[k]
 
 			s.Uses = step.Uses
 			s.ID = nonempty(step.ID, "#step-"+strconv.Itoa(k))
 			s.Desc = step.Desc
-			s.With = iface.Step(trace.With(ctx, "step", s.ID))
+			s.WaitFor = step.WaitFor
+
+			e := p.step(ctx, j, s, &step)
+			if e != nil {
+				err = errors.Join(err, prefix(e, "%s/%s", j.ID, s.ID))
+				continue
+			}
 
 			if _, ok := uniq[s.ID]; ok {
-				return nil, errors.New("error reading plugin %q step: duplicate id %q", step.Uses, s.ID)
+				err = errors.Join(err, errors.New("%s: error reading plugin %q step: duplicate id %q", j.ID, step.Uses, s.ID))
+				continue
 			}
 
 			uniq[s.ID] = struct{}{}
 
-			if err := yaml.Unmarshal(step.With, s.With); err != nil {
-				return nil, errors.New("%s: error reading plugin %q step: %w", s.ID, step.Uses, err)
-			}
-
 			slog.Debug("wiring steps",
 				"id", s.ID,
 				"step", step.Uses,
@@ -164,26 +516,195 @@ func (p *P) Parse(ctx context.Context, q []byte) (*Workflow, error) {
 			tr.WireStep(k, &step, s.With)
 		}
 
-		for k := range j.Plugins {
-			p := &j.Plugins[k]
+		if e := waitFor(j.Steps); e != nil {
+			err = errors.Join(err, errors.New("%s: error reading job: %w", j.ID, e))
+		}
 
-			slog.Debug("initializing plugins",
-				"index", k,
-				"plugin", p.Uses,
-			)
+		ids := make([]string, len(j.Steps))
+		for k := range j.Steps {
+			ids[k] = j.Steps[k].ID
+		}
 
-			init, ok := p.With.(Initializer)
-			if !ok {
-				return nil, errors.New("error initializing plugin %q: does not implement proto.Initializer", p.Uses)
+		p.t.Jobs.Store(j.ID, ids)
+	}
+
+	return &w, err
+}
+
+func (p *P) step(ctx context.Context, j *Job, s *Step, step *wire.Step) (err error) {
+	iface, err := p.lookup(step.Uses)
+	if err != nil {
+		return errors.New("error reading plugin %q step: %w", step.Uses, err)
+	}
+
+	if strings.HasPrefix(step.ID, "#") {
+		return errors.New("error reading plugin %q step: id cannot start with #", step.Uses)
+	}
+
+	var e error
+
+	if s.Defer, e = duration(step.Defer); e != nil {
+		err = errors.Join(err, errors.New("error reading step defer: %w", e))
+	}
+
+	if s.Timeout, e = duration(step.Timeout); e != nil {
+		err = errors.Join(err, errors.New("error reading step timeout: %w", e))
+	}
+
+	plugin, e := j.plugin(ctx, p, iface, step.Plugin)
+	if e != nil {
+		return errors.Join(err, errors.New("error reading plugin %q step: %w", step.Uses, e))
+	}
+
+	impl, ok := plugin.With.(Interface)
+	if !ok {
+		return errors.Join(err, errors.New("error reading plugin %q step: does not implement proto.Interface", step.Uses))
+	}
+
+	s.With = impl.Step(trace.With(ctx, "step", s.ID))
+
+	if e := yaml.Unmarshal(step.With, s.With); e != nil {
+		err = errors.Join(err, errors.New("error reading plugin %q step: %w", step.Uses, e))
+	}
+
+	return err
+}
+
+// prefix prefixes every error joined in err.
+func prefix(err error, format string, args ...any) error {
+	if err == nil {
+		return nil
+	}
+
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, err := range j.Unwrap() {
+			errs = append(errs, prefix(err, format, args...))
+		}
+		return errors.Join(errs...)
+	}
+
+	return errors.New(format+": %w", append(args, err)...)
+}
+
+// plugin returns the job plugin a step using iface runs on: the one
+// with the given id or, without an id, the first one that uses iface,
+// configuring a new one when the job does not declare it.
+func (j *Job) plugin(ctx context.Context, p *P, iface Interface, id string) (*Plugin, error) {
+	for i := range j.Plugins {
+		q := &j.Plugins[i]
+
+		if q.Uses != iface.Name() || q.With == nil {
+			continue
+		}
+
+		if id == "" || q.ID == id {
+			return q, nil
+		}
+	}
+
+	if id != "" {
+		return nil, errors.New("plugin %q is not declared by the job", id)
+	}
+
+	j.Plugins = append(j.Plugins, Plugin{
+		Uses: iface.Name(),
+		With: iface.Plugin(ctx, p),
+	})
+
+	return &j.Plugins[len(j.Plugins)-1], nil
+}
+
+// needs ensures every job needs only existing jobs
+// and that the dependency graph has no cycles.
+func needs(jobs []wire.Job) error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	var (
+		deps  = make(map[string][]string, len(jobs))
+		state = make(map[string]int, len(jobs))
+		visit func(id string, path []string) error
+	)
+
+	for i, j := range jobs {
+		deps[jobID(i, &j)] = j.Needs
+	}
+
+	visit = func(id string, path []string) error {
+		switch state[id] {
+		case visiting:
+			return errors.New("needs cycle detected: %s", strings.Join(append(path, id), " -> "))
+		case visited:
+			return nil
+		}
+
+		state[id] = visiting
+
+		for _, dep := range deps[id] {
+			if _, ok := deps[dep]; !ok {
+				return errors.New("%s: needs unknown job %q", id, dep)
 			}
 
-			if err := init.Init(ctx, j); err != nil {
-				return nil, errors.New("error initializing plugin %q: %w", p.Uses, err)
+			if err := visit(dep, append(path, id)); err != nil {
+				return err
 			}
 		}
+
+		state[id] = visited
+
+		return nil
+	}
+
+	for i, j := range jobs {
+		if err := visit(jobID(i, &j), nil); err != nil {
+			return err
+		}
 	}
 
-	return &w, nil
+	return nil
+}
+
+func jobID(i int, job *wire.Job) string {
+	return nonempty(job.ID, "#job-"+strconv.Itoa(i))
+}
+
+// waitFor ensures every wait_for references another step
+// of the same job and that steps do not wait on each other.
+func waitFor(steps []Step) error {
+	ids := make(map[string]string, len(steps))
+
+	for _, s := range steps {
+		ids[s.ID] = s.WaitFor
+	}
+
+	for _, s := range steps {
+		if s.WaitFor == "" {
+			continue
+		}
+
+		if _, ok := ids[s.WaitFor]; !ok {
+			return errors.New("%s: wait_for references unknown step %q", s.ID, s.WaitFor)
+		}
+
+		for id, n := s.WaitFor, 0; id != ""; id, n = ids[id], n+1 {
+			if id == s.ID || n > len(steps) {
+				return errors.New("%s: wait_for cycle detected", s.ID)
+			}
+		}
+	}
+
+	return nil
+}
+
+func duration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
 }
 
 func nonempty[T comparable](t ...T) T {
@@ -4,9 +4,11 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"hookt.dev/cmd/pkg/internal/testutil"
 	"hookt.dev/cmd/pkg/plugin/builtin"
 	"hookt.dev/cmd/pkg/proto"
 
@@ -37,6 +39,8 @@ func newP() *proto.P {
 }
 
 func TestParse(t *testing.T) {
+	t.Setenv("NATS_URL", "nats://127.0.0.1:1")
+
 	p := newP()
 	q := file(t, "../testdata/ok.yaml")
 	ctx := context.Background()
@@ -46,9 +50,108 @@ func TestParse(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_ = w
+	if len(w.Jobs) != 1 || len(w.Jobs[0].Steps) != 3 {
+		t.Fatalf("Parse()=%+v", w)
+	}
+
+	// Parse does not connect, so the unreachable server
+	// is reported only once the workflow starts.
+	if err := w.Start(ctx); err == nil {
+		t.Error("Start()=nil, want connection error")
+	}
 
-	// spew.Dump(w)
+	if err := w.Close(); err != nil {
+		t.Errorf("Close()=%v", err)
+	}
+}
+
+func TestParsePlugin(t *testing.T) {
+	const plugins = `
+jobs:
+  - id: a
+    plugins:
+      - id: one
+        uses: http
+        with: {timeout: 1s}
+      - id: two
+        uses: http
+        with: {timeout: 2s}
+    steps:
+`
+
+	cases := map[string]struct {
+		step    string
+		err     string
+		plugins int
+	}{
+		"by id": {
+			step: `
+      - uses: http
+        plugin: two
+        with:
+          request: {url: http://localhost}
+`,
+		},
+		"first": {
+			step: `
+      - uses: http
+        with:
+          request: {url: http://localhost}
+`,
+		},
+		"unknown id": {
+			step: `
+      - uses: http
+        plugin: three
+        with:
+          request: {url: http://localhost}
+`,
+			err: `a/#step-0: error reading plugin "http" step: plugin "three" is not declared by the job`,
+		},
+		"undeclared": {
+			step: `
+      - uses: event
+        with:
+          match: {.x: 1}
+`,
+			plugins: 3,
+		},
+	}
+
+	for name, cas := range cases {
+		t.Run(name, func(t *testing.T) {
+			w, err := newP().Parse(context.Background(), []byte(plugins+cas.step))
+			if cas.err != "" {
+				if err == nil || !strings.Contains(err.Error(), cas.err) {
+					t.Fatalf("Parse()=%v, want error containing %q", err, cas.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse()=%+v", err)
+			}
+
+			want := max(cas.plugins, 2)
+			if len(w.Jobs[0].Plugins) != want {
+				t.Errorf("Parse() configured %d plugins, want %d", len(w.Jobs[0].Plugins), want)
+			}
+		})
+	}
+}
+
+func TestStart(t *testing.T) {
+	t.Setenv("NATS_URL", testutil.NATSServer(t))
+
+	w, err := newP().Parse(context.Background(), file(t, "../testdata/ok.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer w.Close()
+
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatalf("Start()=%v", err)
+	}
 }
 
 func file(t *testing.T, path string) []byte {
@@ -61,3 +164,63 @@ func file(t *testing.T, path string) []byte {
 
 	return p
 }
+
+func TestValidate(t *testing.T) {
+	const q = `
+jobs:
+  - id: a
+    plugins:
+      - id: file
+        uses: inline
+        with:
+          publish:
+            file: /does/not/exist
+      - uses: event
+        with:
+          sources: [file, missing]
+      - uses: http
+        with: {timeout: 5s, retries: 3}
+    steps:
+      - uses: http
+        timeout: soon
+        with:
+          request:
+            url: ${{ var "x" }
+          response:
+            pass:
+              .status[: 200
+      - uses: event
+        with:
+          match:
+            .x: ${{ nosuchfunc }}
+  - id: b
+    needs: [c]
+    steps:
+      - uses: nope
+        with: {a: 1}
+`
+
+	err := newP().Validate(context.Background(), []byte(q))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	for _, want := range []string{
+		`jobs[0].plugins[2].with.retries: unknown key "retries"`,
+		`jobs[0].steps[0].with.request.url: failed to parse template`,
+		`jobs[0].steps[1].with.match[".x"]: failed to parse template`,
+		`b: needs unknown job "c"`,
+		`a/#step-0: error reading step timeout`,
+		`b/#step-0: error reading plugin "nope" step: not found`,
+		`a: error validating plugin "event": source "missing" not found`,
+		`a/#step-0: error validating plugin "http" step: failed to parse jq ".status["`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate()=%v, want error containing %q", err, want)
+		}
+	}
+
+	if err := newP().Validate(context.Background(), file(t, "../testdata/ok.yaml")); err != nil {
+		t.Errorf("Validate()=%v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package proto
+
+import (
+	"slices"
+	"strings"
+
+	"hookt.dev/cmd/pkg/errors"
+)
+
+// Registry holds plugins by name.
+type Registry map[string]Interface
+
+// Register adds the plugins, rejecting unnamed plugins and names
+// that are already registered.
+func (r Registry) Register(plugins ...Interface) error {
+	var err error
+
+	for _, plugin := range plugins {
+		name := plugin.Name()
+
+		switch _, ok := r[name]; {
+		case name == "":
+			err = errors.Join(err, errors.New("plugin %T has no name", plugin))
+		case ok:
+			err = errors.Join(err, errors.New("plugin %q is already registered", name))
+		default:
+			r[name] = plugin
+		}
+	}
+
+	return err
+}
+
+// Info describes a registered plugin.
+type Info struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Plugins lists the registered plugins by name.
+func (r Registry) Plugins() []Info {
+	infos := make([]Info, 0, len(r))
+
+	for name, plugin := range r {
+		info := Info{Name: name}
+
+		if d, ok := plugin.(Documenter); ok {
+			info.Description = d.Description()
+		}
+
+		infos = append(infos, info)
+	}
+
+	slices.SortFunc(infos, func(a, b Info) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return infos
+}
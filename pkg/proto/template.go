@@ -3,11 +3,13 @@ package proto
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"math/rand"
 	"os"
 	"slices"
 	"strings"
 	"text/template"
+	"time"
 
 	"hookt.dev/cmd/pkg/async"
 	"hookt.dev/cmd/pkg/errors"
@@ -19,26 +21,45 @@ import (
 
 type TOption func(*template.Template) *template.Template
 
+// DefaultVarTimeout is how long the var template function
+// waits for a variable to be set when the context has no deadline.
+const DefaultVarTimeout = 30 * time.Second
+
 type T struct {
-	Options []TOption
-	Vars    *async.Map
+	Options    []TOption
+	Vars       *async.Map
+	Steps      *async.Map
+	Jobs       *async.Map // step ids of every job
+	VarTimeout time.Duration
 }
 
 func NewT() *T {
 	return &T{
-		Vars: &async.Map{},
+		Vars:       &async.Map{},
+		Steps:      &async.Map{},
+		Jobs:       &async.Map{},
+		VarTimeout: DefaultVarTimeout,
 	}
 }
 
 func (p *P) Evaluate(tmpl string, data any) ([]byte, error) {
+	return p.EvaluateContext(context.Background(), tmpl, data)
+}
+
+// EvaluateContext evaluates tmpl against data, waiting for the
+// variables it reads until ctx is done.
+func (p *P) EvaluateContext(ctx context.Context, tmpl string, data any) ([]byte, error) {
 	var buf bytes.Buffer
 
-	t, err := p.t.Parse("", tmpl)
+	t, err := p.t.ParseContext(ctx, "", tmpl)
 	if err != nil {
 		return nil, errors.New("failed to parse template %q: %w", tmpl, err)
 	}
 
 	if err := t.Execute(&buf, data); err != nil {
+		if f := new(Failure); errors.As(err, &f) {
+			return nil, f
+		}
 		return nil, errors.New("failed to evaluate template %q: %w", tmpl, err)
 	}
 
@@ -47,15 +68,24 @@ func (p *P) Evaluate(tmpl string, data any) ([]byte, error) {
 
 func (t *T) With(opts ...TOption) *T {
 	return &T{
-		Options: append(slices.Clone(t.Options), opts...),
-		Vars:    t.Vars,
+		Options:    append(slices.Clone(t.Options), opts...),
+		Vars:       t.Vars,
+		Steps:      t.Steps,
+		Jobs:       t.Jobs,
+		VarTimeout: t.VarTimeout,
 	}
 }
 
 func (t *T) Parse(name, data string) (*template.Template, error) {
+	return t.ParseContext(context.Background(), name, data)
+}
+
+// ParseContext is like Parse, the var function of the template
+// waiting for variables until ctx is done.
+func (t *T) ParseContext(ctx context.Context, name, data string) (*template.Template, error) {
 	tmpl := template.New(name).
 		Funcs(sprig.FuncMap()).
-		Funcs(t.funcs()).
+		Funcs(t.funcs(ctx)).
 		Delims("${{", "}}").
 		Option("missingkey=error")
 
@@ -66,26 +96,34 @@ func (t *T) Parse(name, data string) (*template.Template, error) {
 	return tmpl.Parse(data)
 }
 
-func (t *T) funcs() template.FuncMap {
+func (t *T) funcs(ctx context.Context) template.FuncMap {
 	return map[string]any{
 		"xrand":    xrand,
 		"setvar":   t.setvar,
 		"seterror": t.seterror,
-		"var":      t.getvar,
+		"var":      func(name string) (any, error) { return t.getvar(ctx, name) },
+		"step":     func(id string) (any, error) { return t.getstep(ctx, id) },
 		"setenv":   os.Setenv,
 		"env":      os.Getenv,
 	}
 }
 
 func (t *T) Evaluate(tmpl string, data any) ([]byte, error) {
+	return t.EvaluateContext(context.Background(), tmpl, data)
+}
+
+func (t *T) EvaluateContext(ctx context.Context, tmpl string, data any) ([]byte, error) {
 	var buf bytes.Buffer
 
-	tpl, err := t.Parse("", tmpl)
+	tpl, err := t.ParseContext(ctx, "", tmpl)
 	if err != nil {
 		return nil, errors.New("failed to parse template %q: %w", tmpl, err)
 	}
 
 	if err := tpl.Execute(&buf, data); err != nil {
+		if f := new(Failure); errors.As(err, &f) {
+			return nil, f
+		}
 		return nil, errors.New("failed to evaluate template %q: %w", tmpl, err)
 	}
 
@@ -94,7 +132,7 @@ func (t *T) Evaluate(tmpl string, data any) ([]byte, error) {
 
 func (t *T) Match(ctx context.Context, data string) func(context.Context, any) (bool, error) {
 	tr := trace.ContextPattern(ctx)
-	tmpl, err := t.Parse("", data)
+	tmpl, err := t.ParseContext(ctx, "", data)
 	tr.TemplateValue(ctx, data, tmpl, err)
 	if err != nil {
 		return func(context.Context, any) (bool, error) { return false, err }
@@ -108,6 +146,10 @@ func (t *T) Match(ctx context.Context, data string) func(context.Context, any) (
 
 		err := tmpl.Execute(&buf, got)
 		tr.ExecuteMatch(ctx, []byte(data), buf.Bytes(), err)
+		if f := new(Failure); errors.As(err, &f) {
+			tr.SetError(ctx, got, f.Reason)
+			return false, f
+		}
 		if err != nil {
 			return false, errors.New("failed to evaluate %q: %w", data, err)
 		}
@@ -136,14 +178,93 @@ func (t *T) setvar(name string, value any) any {
 	return value
 }
 
-func (t *T) getvar(name string) any {
-	value, _ := t.Vars.Load(name)
-	return value
+// getvar waits for the variable to be set until ctx is done
+// or, when ctx has no deadline, VarTimeout elapses.
+func (t *T) getvar(ctx context.Context, name string) (any, error) {
+	ctx, cancel := t.timeout(ctx)
+	defer cancel()
+
+	value, err := t.Vars.LoadContext(ctx, name)
+	if err != nil {
+		var names []string
+		for _, key := range t.Vars.Keys() {
+			names = append(names, fmt.Sprint(key))
+		}
+
+		slices.Sort(names)
+
+		return nil, errors.New("variable %q is not set (%w), known variables: [%s]", name, err, strings.Join(names, ", "))
+	}
+
+	return value, nil
+}
+
+// timeout bounds ctx by VarTimeout, unless ctx already has
+// a deadline, e.g. the timeout of the step.
+func (t *T) timeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || t.VarTimeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, t.VarTimeout)
+}
+
+// stepKey identifies a step by its job, to which
+// the step template function is scoped.
+type stepKey struct {
+	job, step string
+}
+
+// stepResult is what a step that ended leaves for later steps.
+type stepResult struct {
+	outputs any
+	err     error
+}
+
+// getstep waits for the step of the job in ctx to end, like getvar,
+// returning its outputs. A step the job does not have, or a ctx
+// without a parsed job, fails at once.
+func (t *T) getstep(ctx context.Context, id string) (any, error) {
+	ctx, cancel := t.timeout(ctx)
+	defer cancel()
+
+	job := trace.Get(ctx, "job")
+
+	ids, ok := t.Jobs.Lookup(job)
+	if !ok {
+		return nil, errors.New("step %q is not available outside of a job, got job %q", id, job)
+	}
+	if !slices.Contains(ids.([]string), id) {
+		return nil, errors.New("step %q is not in job %q, known steps: [%s]", id, job, strings.Join(ids.([]string), ", "))
+	}
+
+	key := stepKey{job: job, step: id}
+
+	value, err := t.Steps.LoadContext(ctx, key)
+	if err != nil {
+		return nil, errors.New("step %q of job %q did not end (%w)", id, key.job, err)
+	}
+
+	res := value.(*stepResult)
+	if res.err != nil {
+		return nil, errors.New("step %q of job %q did not pass: %w", id, key.job, res.err)
+	}
+
+	return res.outputs, nil
+}
+
+// Failure is returned by the seterror template function, it fails
+// the evaluated pattern or template with a custom reason.
+type Failure struct {
+	Reason string
+}
+
+func (f *Failure) Error() string {
+	return f.Reason
 }
 
-func (t *T) seterror(err string) bool {
-	// TODO: implement
-	return true
+func (t *T) seterror(reason string) (bool, error) {
+	return false, &Failure{Reason: reason}
 }
 
 func xrand(s string) string {
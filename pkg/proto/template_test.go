@@ -0,0 +1,67 @@
+package proto_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"hookt.dev/cmd/pkg/proto"
+)
+
+func TestEvaluateVar(t *testing.T) {
+	p := proto.New(proto.WithVarTimeout(50 * time.Millisecond))
+
+	if _, err := p.Evaluate(`${{ setvar "url" "http://localhost" }}`, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := p.Evaluate(`${{ var "url" }}`, nil)
+	if err != nil || string(got) != "http://localhost" {
+		t.Fatalf("Evaluate()=%q, %v", got, err)
+	}
+
+	_, err = p.Evaluate(`${{ var "ulr" }}`, nil)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "known variables: [url]") {
+		t.Fatalf("Evaluate()=%v, want timeout listing url", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := p.EvaluateContext(ctx, `${{ var "ulr" }}`, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("EvaluateContext()=%v, want canceled", err)
+	}
+}
+
+func TestEvaluateVarDeadline(t *testing.T) {
+	p := proto.New(proto.WithVarTimeout(50 * time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	time.AfterFunc(200*time.Millisecond, func() {
+		p.Evaluate(`${{ setvar "url" "http://localhost" }}`, nil)
+	})
+
+	got, err := p.EvaluateContext(ctx, `${{ var "url" }}`, nil)
+	if err != nil || string(got) != "http://localhost" {
+		t.Fatalf("EvaluateContext()=%q, %v", got, err)
+	}
+}
+
+func TestEvaluateStepOutsideJob(t *testing.T) {
+	p := proto.New()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := p.EvaluateContext(ctx, `${{ step "create" }}`, nil)
+	if err == nil || !strings.Contains(err.Error(), "not available outside of a job") {
+		t.Fatalf("EvaluateContext()=%v, want job error", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("EvaluateContext() waited for the deadline")
+	}
+}
@@ -0,0 +1,158 @@
+package proto
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+
+	"hookt.dev/cmd/pkg/errors"
+	"hookt.dev/cmd/pkg/proto/wire"
+
+	"sigs.k8s.io/yaml"
+)
+
+// vars sets the variables and inputs of the workflow, unless they
+// are set by WithVars, in which case inputs are converted to their
+// type; a required input must be set.
+func (p *P) vars(raw *wire.Workflow) error {
+	var err error
+
+	for name, value := range raw.Vars {
+		if _, ok := p.overrides[name]; ok {
+			continue
+		}
+
+		p.t.Vars.Store(name, value)
+	}
+
+	names := make([]string, 0, len(raw.Inputs))
+	for name := range raw.Inputs {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		value, e := input(raw.Inputs[name], p.overrides, name)
+		if e != nil {
+			err = errors.Join(err, errors.New("input %q: %w", name, e))
+			continue
+		}
+
+		p.t.Vars.Store(name, value)
+	}
+
+	return err
+}
+
+func input(in wire.Input, overrides map[string]any, name string) (any, error) {
+	value, ok := overrides[name]
+
+	switch {
+	case ok:
+	case in.Default != nil:
+		value = in.Default
+	case in.Required:
+		return nil, errors.New("is required")
+	default:
+		return zero(in.Type)
+	}
+
+	return convert(in.Type, value)
+}
+
+func zero(typ string) (any, error) {
+	switch typ {
+	case "", "string":
+		return "", nil
+	case "number":
+		return float64(0), nil
+	case "boolean":
+		return false, nil
+	case "object":
+		return map[string]any{}, nil
+	case "array":
+		return []any{}, nil
+	default:
+		return nil, errors.New("unsupported type %q", typ)
+	}
+}
+
+// convert converts value to the input type, parsing strings such
+// as the ones given on the command line and formatting the numbers
+// and booleans of var files as strings.
+func convert(typ string, value any) (any, error) {
+	s, isString := value.(string)
+
+	switch typ {
+	case "", "string":
+		if isString {
+			return s, nil
+		}
+
+		switch v := reflect.ValueOf(value); {
+		case v.CanInt():
+			return strconv.FormatInt(v.Int(), 10), nil
+		case v.CanUint():
+			return strconv.FormatUint(v.Uint(), 10), nil
+		case v.CanFloat():
+			return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+		case v.Kind() == reflect.Bool:
+			return strconv.FormatBool(v.Bool()), nil
+		}
+
+		return nil, errors.New("expected string, got %T", value)
+	case "number":
+		if isString {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, errors.New("expected number: %w", err)
+			}
+			return f, nil
+		}
+
+		switch v := reflect.ValueOf(value); {
+		case v.CanInt():
+			return float64(v.Int()), nil
+		case v.CanUint():
+			return float64(v.Uint()), nil
+		case v.CanFloat():
+			return v.Float(), nil
+		}
+
+		return nil, errors.New("expected number, got %T", value)
+	case "boolean":
+		if isString {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return nil, errors.New("expected boolean: %w", err)
+			}
+			return b, nil
+		}
+
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+
+		return nil, errors.New("expected boolean, got %T", value)
+	case "object", "array":
+		if isString {
+			if err := yaml.Unmarshal([]byte(s), &value); err != nil {
+				return nil, errors.New("expected %s: %w", typ, err)
+			}
+		}
+
+		kind := reflect.Map
+		if typ == "array" {
+			kind = reflect.Slice
+		}
+
+		if v := reflect.ValueOf(value); !v.IsValid() || v.Kind() != kind {
+			return nil, errors.New("expected %s, got %T", typ, value)
+		}
+
+		return value, nil
+	default:
+		return nil, errors.New("unsupported type %q", typ)
+	}
+}
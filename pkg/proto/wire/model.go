@@ -6,11 +6,24 @@ import (
 )
 
 type Workflow struct {
-	Jobs []Job `json:"jobs"`
+	Vars   map[string]any   `json:"vars,omitempty"`
+	Inputs map[string]Input `json:"inputs,omitempty"`
+	Jobs   []Job            `json:"jobs" jsonschema:"required"`
+}
+
+// Input is a variable the workflow expects to be given when run,
+// e.g. with hkt run --var. Its type is one of string, the default,
+// number, boolean, object and array.
+type Input struct {
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	Default     any    `json:"default,omitempty"`
+	Required    bool   `json:"required,omitempty"`
 }
 
 type Job struct {
 	ID      string   `json:"id,omitempty"`
+	Needs   []string `json:"needs,omitempty"`
 	Plugins []Plugin `json:"plugins"`
 	Steps   []Step   `json:"steps"`
 }
@@ -45,17 +58,18 @@ func (o Object) LogValue() slog.Value {
 }
 
 type Step struct {
-	Uses    string          `json:"uses"`
+	Uses    string          `json:"uses" jsonschema:"required"`
 	ID      string          `json:"id,omitempty"`
 	Desc    string          `json:"desc,omitempty"`
-	With    json.RawMessage `json:"with"`
+	Plugin  string          `json:"plugin,omitempty"`
+	With    json.RawMessage `json:"with" jsonschema:"required"`
 	Defer   string          `json:"defer,omitempty"`
 	Timeout string          `json:"timeout,omitempty"`
 	WaitFor string          `json:"wait_for,omitempty"`
 }
 
 type Plugin struct {
-	Uses string          `json:"uses"`
+	Uses string          `json:"uses" jsonschema:"required"`
 	ID   string          `json:"id,omitempty"`
-	With json.RawMessage `json:"with"`
+	With json.RawMessage `json:"with" jsonschema:"required"`
 }
@@ -11,7 +11,6 @@ import (
 
 type generic map[string]json.RawMessage
 
-// TODO: use OAPI3.1 / JSONSchema
 func XParse(p []byte) (*Workflow, error) {
 	type (
 		state byte
@@ -46,6 +45,14 @@ func XParse(p []byte) (*Workflow, error) {
 		case start:
 			for k, p := range it.data {
 				switch k {
+				case "vars":
+					if err := yamlUnmarshal(p, &w.Vars); err != nil {
+						return nil, errors.New("failed to unmarshal vars: %w", err)
+					}
+				case "inputs":
+					if err := yamlUnmarshal(p, &w.Inputs); err != nil {
+						return nil, errors.New("failed to unmarshal inputs: %w", err)
+					}
 				case "jobs":
 					var jobs []generic
 
@@ -75,6 +82,10 @@ func XParse(p []byte) (*Workflow, error) {
 					if err := yamlUnmarshal(p, &job.ID); err != nil {
 						return nil, errors.New("failed to unmarshal job.id: %w", err)
 					}
+				case "needs":
+					if err := yamlUnmarshal(p, &job.Needs); err != nil {
+						return nil, errors.New("failed to unmarshal job.needs: %w", err)
+					}
 				case "plugins":
 					var plugins []generic
 
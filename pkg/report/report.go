@@ -0,0 +1,245 @@
+package report // import "hookt.dev/cmd/pkg/report"
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"hookt.dev/cmd/pkg/check"
+	"hookt.dev/cmd/pkg/errors"
+)
+
+// Suite is the outcome of a single workflow run.
+type Suite struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+	S        *check.S
+}
+
+type Case struct {
+	Job      string          `json:"job"`
+	Step     string          `json:"step"`
+	Desc     string          `json:"desc,omitempty"`
+	Status   string          `json:"status"`
+	Time     float64         `json:"time"`
+	Error    string          `json:"error,omitempty"`
+	Attempts int             `json:"attempts,omitempty"`
+	Failures []check.Failure `json:"failures,omitempty"`
+}
+
+// Cases returns one case per recorded step of the suite, in workflow
+// order. A suite that failed before running any step yields a single
+// failed case.
+func (s *Suite) Cases() []Case {
+	var cases []Case
+
+	if s.S != nil {
+		for _, r := range s.S.Snapshot() {
+			c := Case{
+				Job:      r.Job,
+				Step:     r.Step,
+				Desc:     r.Desc,
+				Status:   string(r.Status),
+				Time:     r.Duration().Seconds(),
+				Error:    r.Error,
+				Attempts: len(r.Attempts),
+			}
+
+			if r.Status != check.StatusPass {
+				c.Failures = r.Failures()
+			}
+
+			cases = append(cases, c)
+		}
+	}
+
+	if len(cases) == 0 && s.Err != nil {
+		cases = append(cases, Case{
+			Step:   "#workflow",
+			Status: "fail",
+			Time:   s.Duration.Seconds(),
+			Error:  s.Err.Error(),
+		})
+	}
+
+	return cases
+}
+
+// Write writes the suites to the file in the given format.
+// The spec has the form format=path, e.g. junit=out.xml.
+func Write(spec string, suites ...Suite) error {
+	format, path, ok := strings.Cut(spec, "=")
+	if !ok || path == "" {
+		return errors.New("invalid report %q: want format=path", spec)
+	}
+
+	var write func(io.Writer, ...Suite) error
+
+	switch format {
+	case "junit":
+		write = JUnit
+	case "json":
+		write = JSON
+	default:
+		return errors.New("invalid report %q: unsupported format %q", spec, format)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.New("failed to create report: %w", err)
+	}
+
+	if err := write(f, suites...); err != nil {
+		f.Close()
+		return errors.New("failed to write %s report: %w", format, err)
+	}
+
+	return f.Close()
+}
+
+type jsonSuite struct {
+	Name   string  `json:"name"`
+	Status string  `json:"status"`
+	Time   float64 `json:"time"`
+	Error  string  `json:"error,omitempty"`
+	Cases  []Case  `json:"cases"`
+}
+
+// JSON writes the suites as a JSON document.
+func JSON(w io.Writer, suites ...Suite) error {
+	var v struct {
+		Suites []jsonSuite `json:"suites"`
+	}
+
+	for _, s := range suites {
+		js := jsonSuite{
+			Name:   s.Name,
+			Status: "pass",
+			Time:   s.Duration.Seconds(),
+			Cases:  s.Cases(),
+		}
+
+		if s.Err != nil {
+			js.Status = "fail"
+			js.Error = s.Err.Error()
+		}
+
+		v.Suites = append(v.Suites, js)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     float64      `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     float64     `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit writes the suites as a JUnit XML document.
+func JUnit(w io.Writer, suites ...Suite) error {
+	var v junitSuites
+
+	for _, s := range suites {
+		js := junitSuite{
+			Name: s.Name,
+			Time: s.Duration.Seconds(),
+		}
+
+		for _, c := range s.Cases() {
+			jc := junitCase{
+				Name:      name(c),
+				Classname: strings.TrimSuffix(s.Name+"."+c.Job, "."),
+				Time:      c.Time,
+			}
+
+			switch check.Status(c.Status) {
+			case check.StatusFail, check.StatusError, check.StatusTimeout:
+				jc.Failure = &junitMessage{
+					Message: c.Error,
+					Text:    failures(c.Failures),
+				}
+				js.Failures++
+			case check.StatusSkip:
+				jc.Skipped = &junitMessage{
+					Message: c.Error,
+				}
+				js.Skipped++
+			}
+
+			js.Cases = append(js.Cases, jc)
+			js.Tests++
+		}
+
+		v.Suites = append(v.Suites, js)
+		v.Tests += js.Tests
+		v.Failures += js.Failures
+		v.Skipped += js.Skipped
+		v.Time += js.Time
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func name(c Case) string {
+	if c.Desc == "" {
+		return c.Step
+	}
+	return c.Step + ": " + c.Desc
+}
+
+func failures(f []check.Failure) string {
+	var sb strings.Builder
+	for _, f := range f {
+		fmt.Fprintf(&sb, "key: %s\ngot: %v\nexpected: %v\n", f.Key, f.Got, f.Expected)
+		if f.Error != "" {
+			fmt.Fprintf(&sb, "error: %s\n", f.Error)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,104 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"hookt.dev/cmd/pkg/check"
+	"hookt.dev/cmd/pkg/report"
+)
+
+func suites() []report.Suite {
+	var s check.S
+
+	// Records are begun in run order, but reported in workflow order.
+	after := s.Begin(&check.Record{Job: "after", JobIndex: 1, Step: "later", Index: 0})
+
+	s.Finish(s.Begin(&check.Record{Job: "job", Step: "ok", Index: 0}), nil)
+
+	bad := s.Begin(&check.Record{Job: "job", Step: "bad", Desc: "checks status", Index: 1})
+	bad.MarkPattern("pass", ".status", check.Value{Got: 500, Want: 200})
+	s.Finish(bad, errors.New("response did not match pass pattern"))
+
+	s.Skip(after, `job "job" did not pass`)
+
+	return []report.Suite{
+		{Name: "a.yaml", S: &s, Err: errors.New("failed")},
+		{Name: "b.yaml", Err: errors.New("failed to parse file")},
+	}
+}
+
+func TestJUnit(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := report.JUnit(&buf, suites()...); err != nil {
+		t.Fatal(err)
+	}
+
+	var v struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Skipped  int `xml:"skipped,attr"`
+		Suites   []struct {
+			Cases []struct {
+				Name    string `xml:"name,attr"`
+				Failure *struct {
+					Message string `xml:"message,attr"`
+					Text    string `xml:",chardata"`
+				} `xml:"failure"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+
+	if err := xml.Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatalf("xml.Unmarshal()=%+v\n%s", err, buf.Bytes())
+	}
+
+	if v.Tests != 4 || v.Failures != 2 || v.Skipped != 1 {
+		t.Fatalf("got tests=%d failures=%d skipped=%d, want 4, 2, 1", v.Tests, v.Failures, v.Skipped)
+	}
+
+	bad := v.Suites[0].Cases[1]
+	if bad.Name != "bad: checks status" || bad.Failure == nil {
+		t.Fatalf("got %+v, want failed bad step", bad)
+	}
+
+	if want := "key: .status\ngot: 500\nexpected: 200\n"; bad.Failure.Text != want {
+		t.Errorf("got failure %q, want %q", bad.Failure.Text, want)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := report.JSON(&buf, suites()...); err != nil {
+		t.Fatal(err)
+	}
+
+	var v struct {
+		Suites []struct {
+			Name   string        `json:"name"`
+			Status string        `json:"status"`
+			Cases  []report.Case `json:"cases"`
+		} `json:"suites"`
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(v.Suites) != 2 || len(v.Suites[0].Cases) != 3 || len(v.Suites[1].Cases) != 1 {
+		t.Fatalf("got %+v", v)
+	}
+
+	if c := v.Suites[0].Cases[1]; c.Status != "fail" || len(c.Failures) != 1 || c.Failures[0].Key != ".status" {
+		t.Errorf("got %+v, want failed .status", c)
+	}
+
+	if c := v.Suites[0].Cases[2]; c.Job != "after" || c.Status != "skip" {
+		t.Errorf("got %+v, want skipped step of job after", c)
+	}
+}
@@ -0,0 +1,189 @@
+package schema // import "hookt.dev/cmd/pkg/schema"
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"hookt.dev/cmd/pkg/proto/wire"
+)
+
+const (
+	Draft = "https://json-schema.org/draft/2020-12/schema"
+	ID    = "https://hookt.dev/schema/workflow.json"
+)
+
+// Schema is the subset of JSON Schema used to describe workflows.
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	ID          string `json:"$id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Type                 string             `json:"type,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Not                  *Schema            `json:"not,omitempty"`
+
+	AllOf []*Schema `json:"allOf,omitempty"`
+	If    *Schema   `json:"if,omitempty"`
+	Then  *Schema   `json:"then,omitempty"`
+}
+
+// Never is a schema no value is valid against.
+var Never = &Schema{Not: &Schema{}}
+
+func (s *Schema) String() string {
+	p, _ := json.MarshalIndent(s, "", "  ")
+	return string(p)
+}
+
+// Plugin describes the wire types a plugin reads from
+// its configuration and from its steps.
+type Plugin struct {
+	Name   string
+	Config any
+	Step   any
+}
+
+// Workflow returns the schema of a workflow file, constraining
+// the with objects of the given plugins by their wire types.
+func Workflow(plugins ...Plugin) *Schema {
+	s := Reflect(wire.Workflow{})
+
+	s.Schema = Draft
+	s.ID = ID
+	s.Title = "hookt workflow"
+
+	var (
+		job    = s.Properties["jobs"].Items
+		plugin = job.Properties["plugins"].Items
+		step   = job.Properties["steps"].Items
+	)
+
+	for _, p := range plugins {
+		if p.Config != nil {
+			plugin.AllOf = append(plugin.AllOf, with(p.Name, p.Config))
+		}
+		if p.Step != nil {
+			step.AllOf = append(step.AllOf, with(p.Name, p.Step))
+		}
+	}
+
+	return s
+}
+
+func with(name string, v any) *Schema {
+	return &Schema{
+		If: &Schema{
+			Properties: map[string]*Schema{
+				"uses": {Const: name},
+			},
+			Required: []string{"uses"},
+		},
+		Then: &Schema{
+			Properties: map[string]*Schema{
+				"with": Reflect(v),
+			},
+		},
+	}
+}
+
+var (
+	generic = reflect.TypeOf(wire.Generic{})
+	object  = reflect.TypeOf(wire.Object{})
+)
+
+// Reflect returns the schema of the type of v, following its
+// json struct tags. Fields tagged with jsonschema:"required"
+// are required.
+func Reflect(v any) *Schema {
+	return reflectType(reflect.TypeOf(v))
+}
+
+func reflectType(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case generic:
+		return &Schema{}
+	case object:
+		return &Schema{
+			Type:                 "object",
+			AdditionalProperties: &Schema{},
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{
+			Type:  "array",
+			Items: reflectType(t.Elem()),
+		}
+	case reflect.Map:
+		return &Schema{
+			Type:                 "object",
+			AdditionalProperties: reflectType(t.Elem()),
+		}
+	case reflect.Struct:
+		s := &Schema{
+			Type:                 "object",
+			Properties:           make(map[string]*Schema),
+			AdditionalProperties: Never,
+		}
+		fields(s, t)
+		return s
+	default:
+		return &Schema{}
+	}
+}
+
+func fields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields(s, ft)
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		s.Properties[name] = reflectType(f.Type)
+
+		if f.Tag.Get("jsonschema") == "required" {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
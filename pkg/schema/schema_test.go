@@ -0,0 +1,117 @@
+package schema_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"hookt.dev/cmd/pkg/plugin/builtin"
+	"hookt.dev/cmd/pkg/proto"
+	"hookt.dev/cmd/pkg/schema"
+)
+
+func workflow() *schema.Schema {
+	var plugins []proto.Interface
+	for _, p := range builtin.Plugins() {
+		plugins = append(plugins, p)
+	}
+	return proto.New(proto.WithPlugins(plugins...)).Schema()
+}
+
+func TestValidate(t *testing.T) {
+	cases := map[string]struct {
+		file string
+		errs []string
+	}{
+		"ok": {
+			file: "../testdata/ok.yaml",
+		},
+		"job": {
+			file: "../testdata/bad/1.yaml",
+			errs: []string{
+				`3:5: jobs[0].bad: unknown key "bad"`,
+				`20:11: jobs[0].plugins[3].with.handle: unknown key "handle"`,
+			},
+		},
+		"plugin": {
+			file: "../testdata/bad/2.yaml",
+			errs: []string{
+				`5:9: jobs[0].plugins[0].bad: unknown key "bad"`,
+			},
+		},
+		"step": {
+			file: "../testdata/bad/3.yaml",
+			errs: []string{
+				`27:9: jobs[0].steps[0].bad: unknown key "bad"`,
+				`39:13: jobs[0].steps[0].with.response.code: unknown key "code"`,
+				`46:11: jobs[0].steps[1].with.on: unknown key "on"`,
+			},
+		},
+	}
+
+	s := workflow()
+
+	for name, cas := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := os.ReadFile(cas.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = s.Validate(p)
+			if len(cas.errs) == 0 {
+				if err != nil {
+					t.Fatalf("Validate()=%v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected error")
+			}
+
+			for _, want := range cas.errs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate()=%v, want error containing %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateTypes(t *testing.T) {
+	const p = `
+jobs:
+  - steps:
+      - uses: http
+        timeout: [5s]
+        with:
+          request:
+            method: GET
+      - uses: nats
+        with:
+          publish:
+            data: {}
+`
+
+	err := workflow().Validate([]byte(p))
+
+	var e *schema.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("Validate()=%v, want *schema.Error", err)
+	}
+
+	if e.Path != "jobs[0].steps[0].timeout" || e.Line != 5 || e.Column != 18 {
+		t.Errorf("got %+v", e)
+	}
+
+	for _, want := range []string{
+		`jobs[0].steps[0].with.request: missing required key "url"`,
+		`jobs[0].steps[1].with.publish: missing required key "subject"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate()=%v, want error containing %q", err, want)
+		}
+	}
+}
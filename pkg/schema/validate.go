@@ -0,0 +1,210 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"hookt.dev/cmd/pkg/errors"
+
+	"sigs.k8s.io/yaml/goyaml.v3"
+)
+
+// Error describes a value of a workflow file that is
+// not valid against the schema.
+type Error struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", e.Line, e.Column, e.Path, e.Message)
+}
+
+// Validate validates the YAML document p against the schema,
+// reporting every invalid value as an *Error.
+func (s *Schema) Validate(p []byte) error {
+	var doc yaml.Node
+
+	if err := yaml.Unmarshal(p, &doc); err != nil {
+		return errors.New("failed to parse yaml: %w", err)
+	}
+
+	if len(doc.Content) == 0 {
+		return &Error{Line: 1, Column: 1, Message: "empty document"}
+	}
+
+	var v validator
+
+	v.validate(s, doc.Content[0], "")
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+
+	slices.SortStableFunc(v.errs, func(a, b *Error) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Column - b.Column
+	})
+
+	errs := make([]error, len(v.errs))
+	for i, err := range v.errs {
+		errs[i] = err
+	}
+
+	return errors.Join(errs...)
+}
+
+type validator struct {
+	errs []*Error
+}
+
+func (v *validator) fail(n *yaml.Node, path, format string, args ...any) {
+	v.errs = append(v.errs, &Error{
+		Path:    path,
+		Line:    n.Line,
+		Column:  n.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// matches reports whether n is valid against s without
+// recording any errors.
+func matches(s *Schema, n *yaml.Node) bool {
+	var v validator
+	v.validate(s, n, "")
+	return len(v.errs) == 0
+}
+
+func (v *validator) validate(s *Schema, n *yaml.Node, path string) {
+	for n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+
+	if s == nil || isNull(n) {
+		return
+	}
+
+	if s.Not != nil && matches(s.Not, n) {
+		v.fail(n, path, "value is not allowed")
+		return
+	}
+
+	if s.Type != "" && !hasType(n, s.Type) {
+		v.fail(n, path, "expected %s, got %s", s.Type, kind(n))
+		return
+	}
+
+	if s.Const != nil && (n.Kind != yaml.ScalarNode || n.Value != fmt.Sprint(s.Const)) {
+		v.fail(n, path, "expected %v", s.Const)
+		return
+	}
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		v.object(s, n, path)
+	case yaml.SequenceNode:
+		if s.Items != nil {
+			for i, item := range n.Content {
+				v.validate(s.Items, item, path+"["+strconv.Itoa(i)+"]")
+			}
+		}
+	}
+
+	for _, s := range s.AllOf {
+		v.validate(s, n, path)
+	}
+
+	if s.If != nil && matches(s.If, n) {
+		v.validate(s.Then, n, path)
+	}
+}
+
+func (v *validator) object(s *Schema, n *yaml.Node, path string) {
+	seen := make(map[string]bool, len(n.Content)/2)
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		var (
+			key, value = n.Content[i], n.Content[i+1]
+			path       = Join(path, key.Value)
+		)
+
+		seen[key.Value] = true
+
+		if prop, ok := s.Properties[key.Value]; ok {
+			v.validate(prop, value, path)
+			continue
+		}
+
+		switch s.AdditionalProperties {
+		case nil:
+		case Never:
+			v.fail(key, path, "unknown key %q", key.Value)
+		default:
+			v.validate(s.AdditionalProperties, value, path)
+		}
+	}
+
+	for _, name := range s.Required {
+		if !seen[name] {
+			v.fail(n, path, "missing required key %q", name)
+		}
+	}
+}
+
+var ident = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// Join appends key to path, e.g. jobs[0].steps[2].with or
+// pass[".status"] for keys that are not identifiers.
+func Join(path, key string) string {
+	if !ident.MatchString(key) {
+		return path + "[" + strconv.Quote(key) + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null"
+}
+
+func hasType(n *yaml.Node, typ string) bool {
+	switch typ {
+	case "number":
+		return kind(n) == "number" || kind(n) == "integer"
+	default:
+		return kind(n) == typ
+	}
+}
+
+func kind(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+
+	switch n.ShortTag() {
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!bool":
+		return "boolean"
+	case "!!null":
+		return "null"
+	default:
+		return "string"
+	}
+}
@@ -35,6 +35,8 @@ var (
 		ExecuteMatch:   func(context.Context, []byte, []byte, error) {},
 		UnmarshalMatch: func(context.Context, []byte, any, error) {},
 		EqualMatch:     func(context.Context, any, any, bool) {},
+		SetError:       func(context.Context, any, string) {},
+		QueryError:     func(context.Context, any, error) {},
 		MatchTimeout:   func(context.Context) {},
 	}
 	nopSchedule = ScheduleTrace{
@@ -68,14 +70,19 @@ func NopPattern() PatternTrace {
 }
 
 func LogPattern() PatternTrace {
+	return LogPatternTo(slog.Default())
+}
+
+// LogPatternTo is like LogPattern, logging to l.
+func LogPatternTo(l *slog.Logger) PatternTrace {
 	return PatternTrace{
 		ParseKey: func(ctx context.Context, q *gojq.Query, err error) {
-			tags := append(attrs(ctx))
+			tags := attrs(ctx)
 			if err != nil {
 				tags = append(tags, tint.Err(err))
-				slog.Error("trace: ParseKey", tags...)
+				l.Error("trace: ParseKey", tags...)
 			} else {
-				slog.Info("trace: ParseKey", tags...)
+				l.Info("trace: ParseKey", tags...)
 			}
 		},
 		UnmarshalValue: func(ctx context.Context, p []byte, v any, err error) {
@@ -85,9 +92,9 @@ func LogPattern() PatternTrace {
 			)
 			if err != nil {
 				tags = append(tags, tint.Err(err))
-				slog.Error("trace: UnmarshalValue", tags...)
+				l.Error("trace: UnmarshalValue", tags...)
 			} else {
-				slog.Info("trace: UnmarshalValue", tags...)
+				l.Info("trace: UnmarshalValue", tags...)
 			}
 		},
 		TemplateValue: func(ctx context.Context, value string, t *template.Template, err error) {
@@ -96,9 +103,9 @@ func LogPattern() PatternTrace {
 			)
 			if err != nil {
 				tags = append(tags, tint.Err(err))
-				slog.Error("trace: TemplateValue", tags...)
+				l.Error("trace: TemplateValue", tags...)
 			} else {
-				slog.Info("trace: TemplateValue", tags...)
+				l.Info("trace: TemplateValue", tags...)
 			}
 		},
 		ExecuteMatch: func(ctx context.Context, data, result []byte, err error) {
@@ -108,9 +115,9 @@ func LogPattern() PatternTrace {
 			)
 			if err != nil {
 				tags = append(tags, tint.Err(err))
-				slog.Error("trace: ExecuteMatch", tags...)
+				l.Error("trace: ExecuteMatch", tags...)
 			} else {
-				slog.Info("trace: ExecuteMatch", tags...)
+				l.Info("trace: ExecuteMatch", tags...)
 			}
 		},
 		UnmarshalMatch: func(ctx context.Context, p []byte, v any, err error) {
@@ -120,9 +127,9 @@ func LogPattern() PatternTrace {
 			)
 			if err != nil {
 				tags = append(tags, tint.Err(err))
-				slog.Error("trace: UnmarshalMatch", tags...)
+				l.Error("trace: UnmarshalMatch", tags...)
 			} else {
-				slog.Info("trace: UnmarshalMatch", tags...)
+				l.Info("trace: UnmarshalMatch", tags...)
 			}
 		},
 		EqualMatch: func(ctx context.Context, want any, got any, ok bool) {
@@ -137,69 +144,88 @@ func LogPattern() PatternTrace {
 				),
 			)
 			if !ok {
-				slog.Error("trace: EqualMatch", tags...)
+				l.Error("trace: EqualMatch", tags...)
 			} else {
-				slog.Info("trace: EqualMatch", tags...)
+				l.Info("trace: EqualMatch", tags...)
 			}
 		},
+		SetError: func(ctx context.Context, got any, reason string) {
+			tags := append(attrs(ctx),
+				"got", got,
+				"reason", reason,
+			)
+			l.Error("trace: SetError", tags...)
+		},
+		QueryError: func(ctx context.Context, obj any, err error) {
+			tags := append(attrs(ctx),
+				"obj", obj,
+				tint.Err(err),
+			)
+			l.Error("trace: QueryError", tags...)
+		},
 		MatchTimeout: func(ctx context.Context) {
-			tags := append(attrs(ctx))
-			slog.Error("trace: MatchTimeout", tags...)
+			tags := attrs(ctx)
+			l.Error("trace: MatchTimeout", tags...)
 		},
 	}
 }
 
 func LogSchedule() ScheduleTrace {
+	return LogScheduleTo(slog.Default())
+}
+
+// LogScheduleTo is like LogSchedule, logging to l.
+func LogScheduleTo(l *slog.Logger) ScheduleTrace {
 	return ScheduleTrace{
 		BeforePublish: func(ctx context.Context, msg *wire.Message) {
 			tags := append(attrs(ctx),
 				"message", len(msg.Bytes()),
 			)
-			slog.Info("trace: BeforePublish", tags...)
+			l.Info("trace: BeforePublish", tags...)
 		},
 		Publish: func(ctx context.Context, msg *wire.Message) {
 			tags := append(attrs(ctx),
 				"message", len(msg.Bytes()),
 			)
-			slog.Info("trace: Publish", tags...)
+			l.Info("trace: Publish", tags...)
 		},
 		BeforeStop: func(ctx context.Context, i int) {
 			tags := append(attrs(ctx),
 				"step", i,
 			)
-			slog.Info("trace: BeforeStop", tags...)
+			l.Info("trace: BeforeStop", tags...)
 		},
 		Stop: func(ctx context.Context, i int) {
 			tags := append(attrs(ctx),
 				"step", i,
 			)
-			slog.Info("trace: Stop", tags...)
+			l.Info("trace: Stop", tags...)
 		},
 		BeforeDemux: func(ctx context.Context, msg Message) {
 			tags := append(attrs(ctx),
 				"message", len(msg.Bytes()),
 			)
-			slog.Info("trace: BeforeDemux", tags...)
+			l.Info("trace: BeforeDemux", tags...)
 		},
 		Demux: func(ctx context.Context, msg Message) {
 			tags := append(attrs(ctx),
 				"message", len(msg.Bytes()),
 			)
-			slog.Info("trace: Demux", tags...)
+			l.Info("trace: Demux", tags...)
 		},
 		BeforeMux: func(ctx context.Context, msg Message, i int) {
 			tags := append(attrs(ctx),
 				"message", len(msg.Bytes()),
 				"step", i,
 			)
-			slog.Info("trace: BeforeMux", tags...)
+			l.Info("trace: BeforeMux", tags...)
 		},
 		Mux: func(ctx context.Context, msg Message, i int) {
 			tags := append(attrs(ctx),
 				"message", len(msg.Bytes()),
 				"step", i,
 			)
-			slog.Info("trace: Mux", tags...)
+			l.Info("trace: Mux", tags...)
 		},
 		Wait: func(ctx context.Context, msg Message, i int, ok bool) {
 			tags := append(attrs(ctx),
@@ -207,9 +233,9 @@ func LogSchedule() ScheduleTrace {
 				"step", i,
 			)
 			if !ok {
-				slog.Error("trace: Wait", tags...)
+				l.Error("trace: Wait", tags...)
 			} else {
-				slog.Info("trace: Wait", tags...)
+				l.Info("trace: Wait", tags...)
 			}
 		},
 		Done: func(ctx context.Context, msg Message, i int) {
@@ -217,13 +243,13 @@ func LogSchedule() ScheduleTrace {
 				"message", len(msg.Bytes()),
 				"step", i,
 			)
-			slog.Info("trace: Done", tags...)
+			l.Info("trace: Done", tags...)
 		},
 		Drain: func(ctx context.Context, i int) {
 			tags := append(attrs(ctx),
 				"step", i,
 			)
-			slog.Info("trace: Drain", tags...)
+			l.Info("trace: Drain", tags...)
 		},
 	}
 }
@@ -364,6 +390,8 @@ type PatternTrace struct {
 	ExecuteMatch   func(context.Context, []byte, []byte, error)
 	UnmarshalMatch func(context.Context, []byte, any, error)
 	EqualMatch     func(context.Context, any, any, bool)
+	SetError       func(context.Context, any, string)
+	QueryError     func(context.Context, any, error)
 	MatchTimeout   func(context.Context)
 }
 
@@ -410,6 +438,20 @@ func (pt PatternTrace) Join(extra PatternTrace) PatternTrace {
 			extra.EqualMatch(ctx, want, got, ok)
 		}
 	}
+	if extra.SetError != nil {
+		fn := pt.SetError
+		pt.SetError = func(ctx context.Context, got any, reason string) {
+			fn(ctx, got, reason)
+			extra.SetError(ctx, got, reason)
+		}
+	}
+	if extra.QueryError != nil {
+		fn := pt.QueryError
+		pt.QueryError = func(ctx context.Context, obj any, err error) {
+			fn(ctx, obj, err)
+			extra.QueryError(ctx, obj, err)
+		}
+	}
 	if extra.MatchTimeout != nil {
 		fn := pt.MatchTimeout
 		pt.MatchTimeout = func(ctx context.Context) {